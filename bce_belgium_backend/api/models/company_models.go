@@ -1,6 +1,7 @@
 package models
 
 type CompanySearchCriteria struct {
+	Query         string `json:"query,omitempty"`
 	NaceCode      string `json:"nace_code,omitempty"`
 	Denomination  string `json:"denomination,omitempty"`
 	ZipCode       string `json:"zipcode,omitempty"`
@@ -33,6 +34,16 @@ type CompanyResult struct {
 	Fax             string `json:"fax,omitempty"`
 	NaceCode        string `json:"nace_code,omitempty"`
 	NaceDescription string `json:"nace_description,omitempty"`
+
+	Score     float64 `json:"score,omitempty"`
+	Highlight string  `json:"highlight,omitempty"`
+}
+
+type Suggestion struct {
+	EntityNumber string `json:"entitynumber"`
+	Denomination string `json:"denomination"`
+	ZipCode      string `json:"zipcode,omitempty"`
+	City         string `json:"city,omitempty"`
 }
 
 type CompanySearchResult struct {
@@ -40,3 +51,61 @@ type CompanySearchResult struct {
 	Results  []CompanyResult       `json:"results"`
 	Meta     Meta                  `json:"meta"`
 }
+
+// EntityDetails are the rows KBO attaches to an entity number: an enterprise,
+// one of its establishments or a branch. Activities are grouped by NACE
+// version, then classification (MAIN, SECO, ANCI).
+type EntityDetails struct {
+	Denominations []map[string]any                       `json:"denominations"`
+	Addresses     []map[string]any                       `json:"addresses"`
+	Contacts      []map[string]any                       `json:"contacts"`
+	Activities    map[string]map[string][]map[string]any `json:"activities"`
+}
+
+type EstablishmentDetail struct {
+	EstablishmentNumber string `json:"establishmentnumber"`
+	StartDate           string `json:"start_date,omitempty"`
+	EntityDetails
+}
+
+type BranchDetail struct {
+	ID        string `json:"id"`
+	StartDate string `json:"start_date,omitempty"`
+	EntityDetails
+}
+
+type CompanyDetail struct {
+	EnterpriseNumber   string `json:"enterprisenumber"`
+	Denomination       string `json:"denomination,omitempty"`
+	Status             string `json:"status"`
+	JuridicalSituation string `json:"juridical_situation,omitempty"`
+	TypeOfEnterprise   string `json:"type_of_enterprise,omitempty"`
+	JuridicalForm      string `json:"juridical_form,omitempty"`
+	JuridicalFormCAC   string `json:"juridical_form_cac,omitempty"`
+	StartDate          string `json:"start_date,omitempty"`
+	EntityDetails
+	Establishments []EstablishmentDetail `json:"establishments"`
+	Branches       []BranchDetail        `json:"branches"`
+}
+
+const (
+	LOOKUP_FOUND     = "found"
+	LOOKUP_NOT_FOUND = "not_found"
+	LOOKUP_INVALID   = "invalid"
+)
+
+type LookupRequest struct {
+	Identifiers []string `json:"identifiers"`
+}
+
+// LookupResult answers one identifier of a batch lookup. Establishment
+// numbers resolve to the enterprise they belong to.
+type LookupResult struct {
+	Input            string         `json:"input"`
+	Kind             string         `json:"kind,omitempty"`
+	Identifier       string         `json:"identifier,omitempty"`
+	Status           string         `json:"status"`
+	Error            string         `json:"error,omitempty"`
+	EnterpriseNumber string         `json:"enterprisenumber,omitempty"`
+	Company          *CompanyResult `json:"company,omitempty"`
+}
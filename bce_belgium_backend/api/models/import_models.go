@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type ImportFile struct {
+	FileName    string     `json:"file_name"`
+	TableName   string     `json:"table_name"`
+	Status      string     `json:"status"`
+	Checksum    string     `json:"checksum,omitempty"`
+	ExtractDate string     `json:"extract_date,omitempty"`
+	Rows        int64      `json:"rows"`
+	Rejects     int        `json:"rejects"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	DurationMs  int64      `json:"duration_ms"`
+}
+
+type ImportRun struct {
+	ID         int64        `json:"id"`
+	Source     string       `json:"source"`
+	Status     string       `json:"status"`
+	Rows       int64        `json:"rows"`
+	Rejects    int          `json:"rejects"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	DurationMs int64        `json:"duration_ms"`
+	Files      []ImportFile `json:"files"`
+}
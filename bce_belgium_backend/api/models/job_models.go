@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type EnrichJob struct {
+	ID               int64      `json:"id"`
+	FileName         string     `json:"file_name"`
+	Status           string     `json:"status"`
+	IdentifierColumn string     `json:"identifier_column,omitempty"`
+	NameColumn       string     `json:"name_column,omitempty"`
+	ZipCodeColumn    string     `json:"zipcode_column,omitempty"`
+	Rows             int        `json:"rows"`
+	Processed        int        `json:"processed"`
+	Matched          int        `json:"matched"`
+	Error            string     `json:"error,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	FinishedAt       *time.Time `json:"finished_at,omitempty"`
+}
+
+// EnrichJobColumns names the columns of an uploaded file used for matching.
+// Empty names are guessed from the header.
+type EnrichJobColumns struct {
+	Identifier string
+	Name       string
+	ZipCode    string
+}
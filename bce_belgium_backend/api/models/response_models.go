@@ -8,13 +8,23 @@ type APIResponse struct {
 }
 
 type Meta struct {
-	Count    int   `json:"count,omitempty"`
-	Total    int   `json:"total,omitempty"`
-	Limit    int   `json:"limit,omitempty"`
-	Offset   int   `json:"offset,omitempty"`
-	Page     int   `json:"page,omitempty"`
-	Pages    int   `json:"pages,omitempty"`
-	Duration int64 `json:"duration_ms,omitempty"`
+	Count      int    `json:"count,omitempty"`
+	Total      int    `json:"total,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	Offset     int    `json:"offset,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	Pages      int    `json:"pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Duration   int64  `json:"duration_ms,omitempty"`
+}
+
+// PageRequest selects a page either by offset or by an opaque keyset cursor.
+// When Cursor is set, Offset is ignored.
+type PageRequest struct {
+	Limit  int
+	Offset int
+	Cursor string
 }
 
 type PaginatedResponse struct {
@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
 	"csv-importer/api/middleware"
 	"csv-importer/api/services/company"
 	"csv-importer/api/services/data"
 	"csv-importer/api/services/export"
+	"csv-importer/api/services/imports"
+	"csv-importer/api/services/jobs"
 	"csv-importer/api/services/search"
 	"csv-importer/api/services/tables"
 	"csv-importer/config"
@@ -28,6 +31,8 @@ type Server struct {
 	tableHandler   *tables.Handler
 	exportHandler  *export.Handler
 	companyHandler *company.Handler
+	importHandler  *imports.Handler
+	jobHandler     *jobs.Handler
 }
 
 func createLogger() *slog.Logger {
@@ -72,6 +77,13 @@ func NewServer(db *sql.DB) *Server {
 	companyService := company.NewCompanyService(db)
 	companyHandler := company.NewHandler(companyService)
 
+	importService := imports.NewImportService(db)
+	importHandler := imports.NewHandler(importService)
+
+	jobService := jobs.NewJobService(db, companyService)
+	jobHandler := jobs.NewHandler(jobService)
+	go jobService.Run(context.Background())
+
 	server := &Server{
 		db:             db,
 		router:         router,
@@ -81,6 +93,8 @@ func NewServer(db *sql.DB) *Server {
 		tableHandler:   tableHandler,
 		exportHandler:  exportHandler,
 		companyHandler: companyHandler,
+		importHandler:  importHandler,
+		jobHandler:     jobHandler,
 	}
 
 	server.setupRoutes()
@@ -159,12 +173,30 @@ func (s *Server) setupRoutes() {
 	}
 
 	companyGroup := api.Group("/companies")
+	companyGroup.Use(middleware.ParseOffsetParam())
 	{
+		companyGroup.GET("/search", s.companyHandler.SearchFullText())
+		companyGroup.GET("/suggest", s.companyHandler.Suggest())
 		companyGroup.GET("/search/nace", s.companyHandler.SearchByNaceCode())
 		companyGroup.GET("/search/denomination", s.companyHandler.SearchByDenomination())
+		companyGroup.GET("/search/name", s.companyHandler.SearchByName())
 		companyGroup.GET("/search/zipcode", s.companyHandler.SearchByZipcode())
 		companyGroup.GET("/search/startdate", s.companyHandler.SearchByStartDate())
 		companyGroup.GET("/search/multi", s.companyHandler.SearchMultiCriteria())
+		companyGroup.POST("/lookup", s.companyHandler.LookupIdentifiers())
+		companyGroup.GET("/:entitynumber", s.companyHandler.GetCompany())
+	}
+
+	api.GET("/imports",
+		middleware.ParseLimitParam(20, 100),
+		s.importHandler.ListImports(),
+	)
+
+	jobGroup := api.Group("/jobs")
+	{
+		jobGroup.POST("/enrich", s.jobHandler.SubmitEnrichJob())
+		jobGroup.GET("/:id", s.jobHandler.GetJob())
+		jobGroup.GET("/:id/result", s.jobHandler.GetJobResult())
 	}
 
 }
@@ -182,18 +214,40 @@ func (s *Server) Start(port string) error {
 	s.logger.Info("🔍 NACE search",
 		slog.String("url", "http://localhost"+port+"/api/search/nacecode"),
 	)
+	s.logger.Info("🔍 Company search",
+		slog.String("url", "http://localhost"+port+"/api/companies/search"),
+	)
+	s.logger.Info("💡 Company suggest",
+		slog.String("url", "http://localhost"+port+"/api/companies/suggest"),
+	)
 	s.logger.Info("🔍 Company search",
 		slog.String("url", "http://localhost"+port+"/api/companies/search/nace"),
 	)
 	s.logger.Info("🔍 Company search",
 		slog.String("url", "http://localhost"+port+"/api/companies/search/denomination"),
 	)
+	s.logger.Info("🔍 Company search",
+		slog.String("url", "http://localhost"+port+"/api/companies/search/name"),
+	)
 	s.logger.Info("🔍 Company search",
 		slog.String("url", "http://localhost"+port+"/api/companies/search/zipcode"),
 	)
 	s.logger.Info("🔍 Company search",
 		slog.String("url", "http://localhost"+port+"/api/companies/search/multi"),
 	)
+	s.logger.Info("🏢 Company detail",
+		slog.String("url", "http://localhost"+port+"/api/companies/:entitynumber"),
+	)
+	s.logger.Info("📋 Company lookup",
+		slog.String("url", "POST http://localhost"+port+"/api/companies/lookup"),
+	)
+
+	s.logger.Info("🗂️ Import history",
+		slog.String("url", "http://localhost"+port+"/api/imports"),
+	)
+	s.logger.Info("🧾 Enrich jobs",
+		slog.String("url", "POST http://localhost"+port+"/api/jobs/enrich"),
+	)
 
 	return s.router.Run(port)
 }
@@ -0,0 +1,71 @@
+package company
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	CURSOR_NEXT = "next"
+	CURSOR_PREV = "prev"
+)
+
+type pageCursor struct {
+	Direction string   `json:"d"`
+	Keys      []string `json:"k"`
+}
+
+func encodeCursor(direction string, keys ...string) string {
+	data, err := json.Marshal(pageCursor{Direction: direction, Keys: keys})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var decoded pageCursor
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	if decoded.Direction != CURSOR_NEXT && decoded.Direction != CURSOR_PREV {
+		return nil, fmt.Errorf("invalid cursor direction")
+	}
+	if len(decoded.Keys) == 0 {
+		return nil, fmt.Errorf("invalid cursor: missing keys")
+	}
+
+	return &decoded, nil
+}
+
+// checkCursor decodes a cursor and checks its keys against the sort of the
+// search it is sent to, so a cursor of another search or an edited one is
+// rejected before its keys reach SQL.
+func checkCursor(cursor string, sortByStartDate bool) error {
+	decoded, err := decodeCursor(cursor)
+	if err != nil {
+		return err
+	}
+
+	keys := 1
+	if sortByStartDate {
+		keys = 2
+	}
+	if len(decoded.Keys) != keys {
+		return fmt.Errorf("invalid cursor for this search")
+	}
+	if sortByStartDate {
+		if _, err := time.Parse("2006-01-02", decoded.Keys[0]); err != nil {
+			return fmt.Errorf("invalid cursor: bad start date")
+		}
+	}
+	return nil
+}
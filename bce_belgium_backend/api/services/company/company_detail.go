@@ -0,0 +1,150 @@
+package company
+
+import (
+	"context"
+	"csv-importer/api/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var ErrCompanyNotFound = errors.New("company not found")
+
+// GetCompanyDetail returns the full profile of one enterprise with its
+// establishments and branches, each with their own denominations, addresses,
+// contacts and activities.
+func (s *companyService) GetCompanyDetail(ctx context.Context, enterpriseNumber string) (*models.CompanyDetail, error) {
+	cacheKey := fmt.Sprintf("companies:detail:%s", enterpriseNumber)
+	var cached models.CompanyDetail
+	if err := s.cache.Get(cacheKey, &cached); err == nil {
+		return &cached, nil
+	}
+
+	detail := &models.CompanyDetail{
+		EntityDetails:  newEntityDetails(),
+		Establishments: []models.EstablishmentDetail{},
+		Branches:       []models.BranchDetail{},
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT enterprisenumber, COALESCE(status, ''), COALESCE(juridicalsituation, ''), COALESCE(typeofenterprise, ''),
+			COALESCE(juridicalform, ''), COALESCE(juridicalformcac, ''), COALESCE(TO_CHAR(startdate, 'DD-MM-YYYY'), '')
+		FROM enterprise
+		WHERE enterprisenumber = $1`, enterpriseNumber).Scan(&detail.EnterpriseNumber, &detail.Status,
+		&detail.JuridicalSituation, &detail.TypeOfEnterprise, &detail.JuridicalForm, &detail.JuridicalFormCAC, &detail.StartDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCompanyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("enterprise query failed: %w", err)
+	}
+
+	enterprise := []string{enterpriseNumber}
+	entities := map[string]*models.EntityDetails{enterpriseNumber: &detail.EntityDetails}
+
+	s.queryEntityRows("establishment",
+		"SELECT establishmentnumber, enterprisenumber, COALESCE(TO_CHAR(startdate, 'DD-MM-YYYY'), '') AS startdate FROM establishment WHERE enterprisenumber IN (%s) ORDER BY establishmentnumber",
+		enterprise, func(_ string, row map[string]any) bool {
+			detail.Establishments = append(detail.Establishments, models.EstablishmentDetail{
+				EstablishmentNumber: fmt.Sprint(row["establishmentnumber"]),
+				StartDate:           fmt.Sprint(row["startdate"]),
+				EntityDetails:       newEntityDetails(),
+			})
+			return true
+		})
+
+	s.queryEntityRows("branch",
+		"SELECT id, enterprisenumber, COALESCE(TO_CHAR(startdate, 'DD-MM-YYYY'), '') AS startdate FROM branch WHERE enterprisenumber IN (%s) ORDER BY id",
+		enterprise, func(_ string, row map[string]any) bool {
+			detail.Branches = append(detail.Branches, models.BranchDetail{
+				ID:            fmt.Sprint(row["id"]),
+				StartDate:     fmt.Sprint(row["startdate"]),
+				EntityDetails: newEntityDetails(),
+			})
+			return true
+		})
+
+	for i := range detail.Establishments {
+		entities[detail.Establishments[i].EstablishmentNumber] = &detail.Establishments[i].EntityDetails
+	}
+	for i := range detail.Branches {
+		entities[detail.Branches[i].ID] = &detail.Branches[i].EntityDetails
+	}
+
+	entityNumbers := make([]string, 0, len(entities))
+	for entityNumber := range entities {
+		entityNumbers = append(entityNumbers, entityNumber)
+	}
+
+	s.loadEntityDetails(entities, entityNumbers)
+
+	for _, row := range detail.Denominations {
+		if row["language"] == "2" {
+			detail.Denomination = fmt.Sprint(row["denomination"])
+			break
+		}
+	}
+	if detail.Denomination == "" && len(detail.Denominations) > 0 {
+		detail.Denomination = fmt.Sprint(detail.Denominations[0]["denomination"])
+	}
+
+	if err := s.cache.Set(cacheKey, detail, 1*time.Hour); err != nil {
+		slog.Error("Cache write failed", "key", cacheKey, "error", err.Error())
+	}
+
+	return detail, nil
+}
+
+func newEntityDetails() models.EntityDetails {
+	return models.EntityDetails{
+		Denominations: []map[string]any{},
+		Addresses:     []map[string]any{},
+		Contacts:      []map[string]any{},
+		Activities:    map[string]map[string][]map[string]any{},
+	}
+}
+
+// loadEntityDetails fills the denominations, addresses, contacts and
+// activities of every entity in a single batched query per table.
+func (s *companyService) loadEntityDetails(entities map[string]*models.EntityDetails, entityNumbers []string) {
+	s.queryEntityRows("denomination",
+		"SELECT entitynumber, language, typeofdenomination, denomination FROM denomination WHERE entitynumber IN (%s) ORDER BY typeofdenomination, language",
+		entityNumbers, func(entityNumber string, row map[string]any) bool {
+			entity := entities[entityNumber]
+			entity.Denominations = append(entity.Denominations, row)
+			return true
+		})
+
+	s.queryEntityRows("address",
+		`SELECT entitynumber, typeofaddress, countrynl, countryfr, zipcode, municipalitynl, municipalityfr,
+			streetnl, streetfr, housenumber, box, extraaddressinfo, TO_CHAR(datestrikingoff, 'DD-MM-YYYY') AS datestrikingoff
+		FROM address WHERE entitynumber IN (%s) ORDER BY typeofaddress`,
+		entityNumbers, func(entityNumber string, row map[string]any) bool {
+			entity := entities[entityNumber]
+			entity.Addresses = append(entity.Addresses, row)
+			return true
+		})
+
+	s.queryEntityRows("contact",
+		"SELECT entitynumber, entitycontact, contacttype, value FROM contact WHERE entitynumber IN (%s) ORDER BY contacttype, value",
+		entityNumbers, func(entityNumber string, row map[string]any) bool {
+			entity := entities[entityNumber]
+			entity.Contacts = append(entity.Contacts, row)
+			return true
+		})
+
+	s.queryEntityRows("activity",
+		"SELECT entitynumber, activitygroup, naceversion, nacecode, classification FROM activity WHERE entitynumber IN (%s) ORDER BY naceversion DESC, classification, nacecode",
+		entityNumbers, func(entityNumber string, row map[string]any) bool {
+			entity := entities[entityNumber]
+			version := fmt.Sprint(row["naceversion"])
+			classification := fmt.Sprint(row["classification"])
+			if entity.Activities[version] == nil {
+				entity.Activities[version] = map[string][]map[string]any{}
+			}
+			entity.Activities[version][classification] = append(entity.Activities[version][classification], row)
+			return true
+		})
+}
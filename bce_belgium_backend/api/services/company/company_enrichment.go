@@ -1,6 +1,7 @@
 package company
 
 import (
+	"context"
 	"csv-importer/api/helpers/utils"
 	"csv-importer/api/models"
 	"fmt"
@@ -8,11 +9,25 @@ import (
 	"strings"
 )
 
-func (s *companyService) enrichCompleteCompanyData(entityNumbers []string, naceCode string) ([]models.CompanyResult, error) {
+// enrichCompleteCompanyData loads the full result of each enterprise, in the
+// given order. It reads the denormalized company_search table and falls back
+// to querying the source tables while that table has not been built yet.
+func (s *companyService) enrichCompleteCompanyData(ctx context.Context, entityNumbers []string, naceCode string) ([]models.CompanyResult, error) {
 	if len(entityNumbers) == 0 {
 		return []models.CompanyResult{}, nil
 	}
 
+	built, err := s.hasCompanySearch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if built {
+		return s.loadCompanySearch(entityNumbers, naceCode)
+	}
+	return s.enrichFromSourceTables(entityNumbers, naceCode)
+}
+
+func (s *companyService) enrichFromSourceTables(entityNumbers []string, naceCode string) ([]models.CompanyResult, error) {
 	companyMap := make(map[string]*models.CompanyResult)
 
 	for _, entityNumber := range entityNumbers {
@@ -53,9 +68,9 @@ func (s *companyService) enrichEnterpriseData(companyMap map[string]*models.Comp
 		entityNumbers = append(entityNumbers, entityNumber)
 	}
 
-	batchSize := 1000
-	for i := 0; i < len(entityNumbers); i += batchSize {
-		end := min(i+batchSize, len(entityNumbers))
+	BATCH_SIZE := 1000
+	for i := 0; i < len(entityNumbers); i += BATCH_SIZE {
+		end := min(i+BATCH_SIZE, len(entityNumbers))
 		batch := entityNumbers[i:end]
 
 		placeholders := make([]string, len(batch))
@@ -66,7 +81,7 @@ func (s *companyService) enrichEnterpriseData(companyMap map[string]*models.Comp
 		}
 
 		query := fmt.Sprintf(`
-			SELECT enterprisenumber, status, juridicalform, startdate
+			SELECT enterprisenumber, status, juridicalform, COALESCE(TO_CHAR(startdate, 'DD-MM-YYYY'), '')
 			FROM enterprise
 			WHERE enterprisenumber IN (%s)
 		`, strings.Join(placeholders, ","))
@@ -183,7 +198,7 @@ func (s *companyService) enrichAllActivities(companyMap map[string]*models.Compa
 
 func (s *companyService) enrichAllEstablishments(companyMap map[string]*models.CompanyResult) {
 	s.enrichTableData(companyMap, "establishment",
-		"SELECT establishmentnumber, enterprisenumber, startdate FROM establishment WHERE enterprisenumber IN (%s)",
+		"SELECT establishmentnumber, enterprisenumber, TO_CHAR(startdate, 'DD-MM-YYYY') AS startdate FROM establishment WHERE enterprisenumber IN (%s)",
 		func(company *models.CompanyResult, row map[string]any) {
 			if company.Establishments == nil {
 				company.Establishments = []map[string]any{}
@@ -202,11 +217,26 @@ func (s *companyService) enrichTableData(companyMap map[string]*models.CompanyRe
 		entityNumbers = append(entityNumbers, entityNumber)
 	}
 
-	batchSize := 1000
+	totalRows := s.queryEntityRows(tableName, queryTemplate, entityNumbers, func(entityNumber string, row map[string]any) bool {
+		company, exists := companyMap[entityNumber]
+		if exists {
+			processRow(company, row)
+		}
+		return exists
+	})
+
+	slog.Info("Enriched table data", "table", tableName, "total_rows", totalRows)
+}
+
+// queryEntityRows runs queryTemplate for entityNumbers in batches of 1000 and
+// hands every row to processRow with its entitynumber (or enterprisenumber).
+// It returns the number of rows processRow accepted.
+func (s *companyService) queryEntityRows(tableName, queryTemplate string, entityNumbers []string, processRow func(string, map[string]any) bool) int {
+	BATCH_SIZE := 1000
 	totalRows := 0
 
-	for i := 0; i < len(entityNumbers); i += batchSize {
-		end := min(i+batchSize, len(entityNumbers))
+	for i := 0; i < len(entityNumbers); i += BATCH_SIZE {
+		end := min(i+BATCH_SIZE, len(entityNumbers))
 		batch := entityNumbers[i:end]
 
 		placeholders := make([]string, len(batch))
@@ -239,21 +269,17 @@ func (s *companyService) enrichTableData(companyMap map[string]*models.CompanyRe
 		}
 
 		for _, row := range data {
-			if entityNumber, ok := row["entitynumber"].(string); ok {
-				if company, exists := companyMap[entityNumber]; exists {
-					processRow(company, row)
-					totalRows++
-				}
-			} else if enterpriseNumber, ok := row["enterprisenumber"].(string); ok {
-				if company, exists := companyMap[enterpriseNumber]; exists {
-					processRow(company, row)
-					totalRows++
-				}
+			entityNumber, ok := row["entitynumber"].(string)
+			if !ok {
+				entityNumber, ok = row["enterprisenumber"].(string)
+			}
+			if ok && processRow(entityNumber, row) {
+				totalRows++
 			}
 		}
 	}
 
-	slog.Info("Enriched table data", "table", tableName, "total_rows", totalRows)
+	return totalRows
 }
 
 func (s *companyService) setLegacyFields(company *models.CompanyResult) {
@@ -2,10 +2,14 @@ package company
 
 import (
 	"csv-importer/api/models"
+	"csv-importer/identifier"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,27 +29,82 @@ func NewHandler(companyService CompanyService) *Handler {
 	}
 }
 
+func parsePageRequest(c *gin.Context, sortByStartDate bool) (models.PageRequest, error) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		return models.PageRequest{}, fmt.Errorf("invalid limit parameter")
+	}
+
+	cursor := c.Query("cursor")
+	if cursor != "" {
+		if err := checkCursor(cursor, sortByStartDate); err != nil {
+			return models.PageRequest{}, err
+		}
+	}
+
+	return models.PageRequest{
+		Limit:  limit,
+		Offset: c.GetInt("offset"),
+		Cursor: cursor,
+	}, nil
+}
+
+// startDateLayouts accepts ISO dates and the Belgian dd-mm-yyyy form used by
+// the KBO extracts.
+var startDateLayouts = []string{"2006-01-02", "02-01-2006", "02/01/2006"}
+
+func parseDate(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	for _, layout := range startDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q (format: YYYY-MM-DD or DD-MM-YYYY)", value)
+}
+
+// parseDateRange normalizes an optional from/to pair to ISO dates.
+func parseDateRange(from, to string) (string, string, error) {
+	from, err := parseDate(from)
+	if err != nil {
+		return "", "", err
+	}
+
+	to, err = parseDate(to)
+	if err != nil {
+		return "", "", err
+	}
+
+	if from != "" && to != "" && from > to {
+		return "", "", fmt.Errorf("'from' date must not be after 'to' date")
+	}
+	return from, to, nil
+}
+
 func (h *Handler) SearchByNaceCode() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		naceCode := c.Query("code")
-		limitStr := c.DefaultQuery("limit", "50")
 
 		if naceCode == "" {
 			c.JSON(400, models.Error("nace code parameter 'code' is required"))
 			return
 		}
 
-		limit, err := strconv.Atoi(limitStr)
-		if err != nil || limit <= 0 || limit > 1000 {
-			c.JSON(400, models.Error("invalid limit parameter"))
+		page, err := parsePageRequest(c, false)
+		if err != nil {
+			c.JSON(400, models.Error(err.Error()))
 			return
 		}
 
-		result, err := h.companyService.SearchByNaceCode(c.Request.Context(), naceCode, limit)
+		result, err := h.companyService.SearchByNaceCode(c.Request.Context(), naceCode, page)
 		if err != nil {
 			slog.Error("failed to search by nace code",
 				slog.String("nace_code", naceCode),
-				slog.Int("limit", limit),
+				slog.Int("limit", page.Limit),
+				slog.Int("offset", page.Offset),
 				slog.String("error", err.Error()),
 			)
 			c.JSON(500, models.Error("search failed: "+err.Error()))
@@ -59,24 +118,86 @@ func (h *Handler) SearchByNaceCode() gin.HandlerFunc {
 func (h *Handler) SearchByDenomination() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		query := c.Query("q")
-		limitStr := c.DefaultQuery("limit", "50")
 
 		if query == "" {
 			c.JSON(400, models.Error("denomination query parameter 'q' is required"))
 			return
 		}
 
-		limit, err := strconv.Atoi(limitStr)
-		if err != nil || limit <= 0 || limit > 1000 {
-			c.JSON(400, models.Error("invalid limit parameter"))
+		page, err := parsePageRequest(c, false)
+		if err != nil {
+			c.JSON(400, models.Error(err.Error()))
 			return
 		}
 
-		result, err := h.companyService.SearchByDenomination(c.Request.Context(), query, limit)
+		result, err := h.companyService.SearchByDenomination(c.Request.Context(), query, page)
 		if err != nil {
 			slog.Error("failed to search by denomination",
 				"query", query,
-				"limit", limit,
+				"limit", page.Limit,
+				"offset", page.Offset,
+				"error", err.Error(),
+			)
+			c.JSON(500, models.Error("search failed: "+err.Error()))
+			return
+		}
+
+		c.JSON(200, models.Success(result))
+	}
+}
+
+func (h *Handler) SearchByName() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Query("q")
+
+		if name == "" {
+			c.JSON(400, models.Error("name query parameter 'q' is required"))
+			return
+		}
+
+		page, err := parsePageRequest(c, false)
+		if err != nil {
+			c.JSON(400, models.Error(err.Error()))
+			return
+		}
+
+		result, err := h.companyService.SearchByName(c.Request.Context(), name, page)
+		if err != nil {
+			slog.Error("failed to search by name",
+				"name", name,
+				"limit", page.Limit,
+				"offset", page.Offset,
+				"error", err.Error(),
+			)
+			c.JSON(500, models.Error("search failed: "+err.Error()))
+			return
+		}
+
+		c.JSON(200, models.Success(result))
+	}
+}
+
+func (h *Handler) SearchFullText() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := c.Query("q")
+
+		if q == "" {
+			c.JSON(400, models.Error("search query parameter 'q' is required"))
+			return
+		}
+
+		page, err := parsePageRequest(c, false)
+		if err != nil {
+			c.JSON(400, models.Error(err.Error()))
+			return
+		}
+
+		result, err := h.companyService.SearchFullText(c.Request.Context(), q, page)
+		if err != nil {
+			slog.Error("failed to run full-text search",
+				"q", q,
+				"limit", page.Limit,
+				"offset", page.Offset,
 				"error", err.Error(),
 			)
 			c.JSON(500, models.Error("search failed: "+err.Error()))
@@ -87,27 +208,50 @@ func (h *Handler) SearchByDenomination() gin.HandlerFunc {
 	}
 }
 
+func (h *Handler) Suggest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := strings.TrimSpace(c.Query("q"))
+
+		if len([]rune(q)) < MIN_SUGGEST_LENGTH {
+			c.JSON(400, models.Error(fmt.Sprintf("query parameter 'q' must have at least %d characters", MIN_SUGGEST_LENGTH)))
+			return
+		}
+
+		suggestions, err := h.companyService.Suggest(c.Request.Context(), q)
+		if err != nil {
+			slog.Error("failed to suggest companies",
+				"q", q,
+				"error", err.Error(),
+			)
+			c.JSON(500, models.Error("suggest failed: "+err.Error()))
+			return
+		}
+
+		c.JSON(200, models.SuccessWithMeta(suggestions, models.Meta{Count: len(suggestions)}))
+	}
+}
+
 func (h *Handler) SearchByZipcode() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		zipcode := c.Query("q")
-		limitStr := c.DefaultQuery("limit", "50")
 
 		if zipcode == "" {
 			c.JSON(400, models.Error("zipcode query parameter 'q' is required"))
 			return
 		}
 
-		limit, err := strconv.Atoi(limitStr)
-		if err != nil || limit <= 0 || limit > 1000 {
-			c.JSON(400, models.Error("invalid limit parameter"))
+		page, err := parsePageRequest(c, false)
+		if err != nil {
+			c.JSON(400, models.Error(err.Error()))
 			return
 		}
 
-		result, err := h.companyService.SearchByZipcode(c.Request.Context(), zipcode, limit)
+		result, err := h.companyService.SearchByZipcode(c.Request.Context(), zipcode, page)
 		if err != nil {
 			slog.Error("failed to search by zipcode",
 				"zipcode", zipcode,
-				"limit", limit,
+				"limit", page.Limit,
+				"offset", page.Offset,
 				"error", err.Error(),
 			)
 			c.JSON(500, models.Error("search failed: "+err.Error()))
@@ -120,27 +264,30 @@ func (h *Handler) SearchByZipcode() gin.HandlerFunc {
 
 func (h *Handler) SearchByStartDate() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		fromDate := c.Query("from")
-		toDate := c.Query("to")
-		limitStr := c.DefaultQuery("limit", "50")
+		if c.Query("from") == "" && c.Query("to") == "" {
+			c.JSON(400, models.Error("'from' or 'to' parameter is required (format: YYYY-MM-DD or DD-MM-YYYY)"))
+			return
+		}
 
-		if fromDate == "" {
-			c.JSON(400, models.Error("start date 'from' parameter is required (format: DD-MM-YYYY)"))
+		fromDate, toDate, err := parseDateRange(c.Query("from"), c.Query("to"))
+		if err != nil {
+			c.JSON(400, models.Error(err.Error()))
 			return
 		}
 
-		limit, err := strconv.Atoi(limitStr)
-		if err != nil || limit <= 0 || limit > 1000 {
-			c.JSON(400, models.Error("invalid limit parameter"))
+		page, err := parsePageRequest(c, true)
+		if err != nil {
+			c.JSON(400, models.Error(err.Error()))
 			return
 		}
 
-		result, err := h.companyService.SearchByStartDate(c.Request.Context(), fromDate, toDate, limit)
+		result, err := h.companyService.SearchByStartDate(c.Request.Context(), fromDate, toDate, page)
 		if err != nil {
 			slog.Error("failed to search by start date",
 				"from_date", fromDate,
 				"to_date", toDate,
-				"limit", limit,
+				"limit", page.Limit,
+				"offset", page.Offset,
 				"error", err.Error(),
 			)
 			c.JSON(500, models.Error("search failed: "+err.Error()))
@@ -153,8 +300,6 @@ func (h *Handler) SearchByStartDate() gin.HandlerFunc {
 
 func (h *Handler) SearchMultiCriteria() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		limitStr := c.DefaultQuery("limit", "50")
-
 		criteria := models.CompanySearchCriteria{
 			NaceCode:      c.Query("nace"),
 			Denomination:  c.Query("denomination"),
@@ -165,22 +310,30 @@ func (h *Handler) SearchMultiCriteria() gin.HandlerFunc {
 		}
 
 		if criteria.NaceCode == "" && criteria.Denomination == "" && criteria.ZipCode == "" &&
-			criteria.Status == "" && criteria.StartDateFrom == "" {
-			c.JSON(400, models.Error("at least one search criteria required (nace, denomination, zipcode, status, startdate_from)"))
+			criteria.Status == "" && criteria.StartDateFrom == "" && criteria.StartDateTo == "" {
+			c.JSON(400, models.Error("at least one search criteria required (nace, denomination, zipcode, status, startdate_from, startdate_to)"))
 			return
 		}
 
-		limit, err := strconv.Atoi(limitStr)
-		if err != nil || limit <= 0 || limit > 1000 {
-			c.JSON(400, models.Error("invalid limit parameter"))
+		var err error
+		criteria.StartDateFrom, criteria.StartDateTo, err = parseDateRange(criteria.StartDateFrom, criteria.StartDateTo)
+		if err != nil {
+			c.JSON(400, models.Error(err.Error()))
 			return
 		}
 
-		result, err := h.companyService.SearchMultiCriteria(c.Request.Context(), criteria, limit)
+		page, err := parsePageRequest(c, false)
+		if err != nil {
+			c.JSON(400, models.Error(err.Error()))
+			return
+		}
+
+		result, err := h.companyService.SearchMultiCriteria(c.Request.Context(), criteria, page)
 		if err != nil {
 			slog.Error("failed to search multi criteria",
 				"criteria", fmt.Sprintf("%+v", criteria),
-				"limit", limit,
+				"limit", page.Limit,
+				"offset", page.Offset,
 				"error", err.Error(),
 			)
 			c.JSON(500, models.Error("search failed: "+err.Error()))
@@ -190,3 +343,65 @@ func (h *Handler) SearchMultiCriteria() gin.HandlerFunc {
 		c.JSON(200, models.Success(result))
 	}
 }
+
+func (h *Handler) GetCompany() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		enterpriseNumber, err := identifier.NormalizeEnterprise(c.Param("entitynumber"))
+		if err != nil {
+			c.JSON(400, models.Error(err.Error()))
+			return
+		}
+
+		detail, err := h.companyService.GetCompanyDetail(c.Request.Context(), enterpriseNumber)
+		if errors.Is(err, ErrCompanyNotFound) {
+			c.JSON(404, models.Error("company "+enterpriseNumber+" not found"))
+			return
+		}
+		if err != nil {
+			slog.Error("failed to get company detail",
+				slog.String("enterprise_number", enterpriseNumber),
+				slog.String("error", err.Error()),
+			)
+			c.JSON(500, models.Error("detail failed: "+err.Error()))
+			return
+		}
+
+		c.JSON(200, models.Success(detail))
+	}
+}
+
+func (h *Handler) LookupIdentifiers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request models.LookupRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(400, models.Error("invalid request body: expected {\"identifiers\": [...]}"))
+			return
+		}
+		if len(request.Identifiers) == 0 {
+			c.JSON(400, models.Error("'identifiers' must contain at least one enterprise or establishment number"))
+			return
+		}
+		if len(request.Identifiers) > MAX_LOOKUP_IDENTIFIERS {
+			c.JSON(400, models.Error(fmt.Sprintf("at most %d identifiers per lookup", MAX_LOOKUP_IDENTIFIERS)))
+			return
+		}
+
+		results, err := h.companyService.LookupIdentifiers(c.Request.Context(), request.Identifiers)
+		if err != nil {
+			slog.Error("failed to lookup identifiers",
+				slog.Int("count", len(request.Identifiers)),
+				slog.String("error", err.Error()),
+			)
+			c.JSON(500, models.Error("lookup failed: "+err.Error()))
+			return
+		}
+
+		found := 0
+		for _, result := range results {
+			if result.Status == models.LOOKUP_FOUND {
+				found++
+			}
+		}
+		c.JSON(200, models.SuccessWithMeta(results, models.Meta{Count: len(results), Total: found}))
+	}
+}
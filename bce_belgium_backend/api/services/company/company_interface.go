@@ -6,9 +6,14 @@ import (
 )
 
 type CompanyService interface {
-	SearchByNaceCode(ctx context.Context, naceCode string, limit int) (*models.CompanySearchResult, error)
-	SearchByDenomination(ctx context.Context, query string, limit int) (*models.CompanySearchResult, error)
-	SearchByZipcode(ctx context.Context, zipcode string, limit int) (*models.CompanySearchResult, error)
-	SearchByStartDate(ctx context.Context, fromDate, toDate string, limit int) (*models.CompanySearchResult, error)
-	SearchMultiCriteria(ctx context.Context, criteria models.CompanySearchCriteria, limit int) (*models.CompanySearchResult, error)
+	SearchByNaceCode(ctx context.Context, naceCode string, page models.PageRequest) (*models.CompanySearchResult, error)
+	SearchByDenomination(ctx context.Context, query string, page models.PageRequest) (*models.CompanySearchResult, error)
+	SearchByName(ctx context.Context, name string, page models.PageRequest) (*models.CompanySearchResult, error)
+	SearchFullText(ctx context.Context, q string, page models.PageRequest) (*models.CompanySearchResult, error)
+	Suggest(ctx context.Context, prefix string) ([]models.Suggestion, error)
+	SearchByZipcode(ctx context.Context, zipcode string, page models.PageRequest) (*models.CompanySearchResult, error)
+	SearchByStartDate(ctx context.Context, fromDate, toDate string, page models.PageRequest) (*models.CompanySearchResult, error)
+	SearchMultiCriteria(ctx context.Context, criteria models.CompanySearchCriteria, page models.PageRequest) (*models.CompanySearchResult, error)
+	GetCompanyDetail(ctx context.Context, enterpriseNumber string) (*models.CompanyDetail, error)
+	LookupIdentifiers(ctx context.Context, inputs []string) ([]models.LookupResult, error)
 }
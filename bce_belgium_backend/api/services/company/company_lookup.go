@@ -0,0 +1,134 @@
+package company
+
+import (
+	"context"
+	"csv-importer/api/models"
+	"csv-importer/identifier"
+	"fmt"
+	"log/slog"
+)
+
+const MAX_LOOKUP_IDENTIFIERS = 1000
+
+// LookupIdentifiers resolves a batch of enterprise and establishment numbers
+// in request order. Establishments are mapped to their enterprise in one
+// query, enterprises are checked in another, and the companies found are
+// loaded together.
+func (s *companyService) LookupIdentifiers(ctx context.Context, inputs []string) ([]models.LookupResult, error) {
+	results := make([]models.LookupResult, len(inputs))
+	var enterpriseNumbers, establishmentNumbers []string
+
+	for i, input := range inputs {
+		results[i].Input = input
+		kind, number, err := identifier.NormalizeEntity(input)
+		if err != nil {
+			results[i].Status = models.LOOKUP_INVALID
+			results[i].Error = err.Error()
+			continue
+		}
+
+		results[i].Kind = kind
+		results[i].Identifier = number
+		if kind == identifier.KIND_ESTABLISHMENT {
+			establishmentNumbers = append(establishmentNumbers, number)
+		} else {
+			enterpriseNumbers = append(enterpriseNumbers, number)
+		}
+	}
+
+	establishments, err := s.lookupEstablishments(ctx, establishmentNumbers)
+	if err != nil {
+		return nil, err
+	}
+	for _, enterpriseNumber := range establishments {
+		enterpriseNumbers = append(enterpriseNumbers, enterpriseNumber)
+	}
+
+	existing, err := s.lookupEnterprises(ctx, enterpriseNumbers)
+	if err != nil {
+		return nil, err
+	}
+
+	companies, err := s.enrichCompleteCompanyData(ctx, existing, "")
+	if err != nil {
+		return nil, err
+	}
+	companyMap := make(map[string]*models.CompanyResult, len(companies))
+	for i := range companies {
+		companyMap[companies[i].EntityNumber] = &companies[i]
+	}
+
+	found := 0
+	for i := range results {
+		result := &results[i]
+		if result.Status == models.LOOKUP_INVALID {
+			continue
+		}
+
+		result.EnterpriseNumber = result.Identifier
+		if result.Kind == identifier.KIND_ESTABLISHMENT {
+			result.EnterpriseNumber = establishments[result.Identifier]
+		}
+
+		result.Company = companyMap[result.EnterpriseNumber]
+		if result.Company == nil {
+			result.Status = models.LOOKUP_NOT_FOUND
+			result.EnterpriseNumber = ""
+			continue
+		}
+		result.Status = models.LOOKUP_FOUND
+		found++
+	}
+
+	slog.Info("Lookup finished", "requested", len(inputs), "found", found)
+	return results, nil
+}
+
+// lookupEstablishments maps each known establishment number to its
+// enterprise number.
+func (s *companyService) lookupEstablishments(ctx context.Context, numbers []string) (map[string]string, error) {
+	establishments := make(map[string]string, len(numbers))
+	if len(numbers) == 0 {
+		return establishments, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT establishmentnumber, enterprisenumber FROM establishment WHERE establishmentnumber = ANY($1)", numbers)
+	if err != nil {
+		return nil, fmt.Errorf("establishment lookup failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var establishmentNumber, enterpriseNumber string
+		if err := rows.Scan(&establishmentNumber, &enterpriseNumber); err != nil {
+			return nil, fmt.Errorf("establishment scan failed: %w", err)
+		}
+		establishments[establishmentNumber] = enterpriseNumber
+	}
+	return establishments, rows.Err()
+}
+
+// lookupEnterprises returns the enterprise numbers that exist, once each.
+func (s *companyService) lookupEnterprises(ctx context.Context, numbers []string) ([]string, error) {
+	if len(numbers) == 0 {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT DISTINCT enterprisenumber FROM enterprise WHERE enterprisenumber = ANY($1)", numbers)
+	if err != nil {
+		return nil, fmt.Errorf("enterprise lookup failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var existing []string
+	for rows.Next() {
+		var enterpriseNumber string
+		if err := rows.Scan(&enterpriseNumber); err != nil {
+			return nil, fmt.Errorf("enterprise scan failed: %w", err)
+		}
+		existing = append(existing, enterpriseNumber)
+	}
+	return existing, rows.Err()
+}
@@ -0,0 +1,198 @@
+package company
+
+import (
+	"context"
+	"csv-importer/api/models"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// searchCompanies pages enterprises matching conditions by keyset on sortKey
+// (a column of src, "" for none) then enterprisenumber, and enriches
+// only the rows of the page.
+func (s *companyService) searchCompanies(ctx context.Context, src searchSource, conditions []string, args []any, page models.PageRequest, cacheKey string, criteria models.CompanySearchCriteria, sortKey string) (*models.CompanySearchResult, error) {
+	pageCacheKey := fmt.Sprintf("%s:l%d:o%d:c%s", cacheKey, page.Limit, page.Offset, page.Cursor)
+	var cached models.CompanySearchResult
+	if err := s.cache.Get(pageCacheKey, &cached); err == nil {
+		return &cached, nil
+	}
+
+	keyColumns := []string{"e.enterprisenumber"}
+	if sortKey != "" {
+		keyColumns = []string{sortKey, "e.enterprisenumber"}
+	}
+
+	var cursor *pageCursor
+	if page.Cursor != "" {
+		decoded, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if len(decoded.Keys) != len(keyColumns) {
+			return nil, fmt.Errorf("invalid cursor for this search")
+		}
+		cursor = decoded
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	dataConditions := slices.Clone(conditions)
+	dataArgs := slices.Clone(args)
+	order := "ASC"
+	offset := page.Offset
+
+	if cursor != nil {
+		offset = 0
+		operator := ">"
+		if cursor.Direction == CURSOR_PREV {
+			operator = "<"
+			order = "DESC"
+		}
+
+		placeholders := make([]string, len(cursor.Keys))
+		for i, key := range cursor.Keys {
+			dataArgs = append(dataArgs, key)
+			placeholders[i] = fmt.Sprintf("$%d", len(dataArgs))
+		}
+		dataConditions = append(dataConditions, fmt.Sprintf("(%s) %s (%s)",
+			strings.Join(keyColumns, ", "), operator, strings.Join(placeholders, ", ")))
+	}
+
+	orderBy := make([]string, len(keyColumns))
+	for i, column := range keyColumns {
+		orderBy[i] = column + " " + order
+	}
+
+	dataWhere := ""
+	if len(dataConditions) > 0 {
+		dataWhere = "WHERE " + strings.Join(dataConditions, " AND ")
+	}
+
+	argN := len(dataArgs) + 1
+	dataQuery := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, strings.Join(keyColumns, "::text, ")+"::text", src.table(), dataWhere, strings.Join(orderBy, ", "), argN, argN+1)
+	dataArgs = append(dataArgs, page.Limit+1, offset)
+
+	countCacheKey := cacheKey + ":count"
+	var totalCount int
+	countCached := s.cache.Get(countCacheKey, &totalCount) == nil
+
+	var countErr error
+	var wg sync.WaitGroup
+
+	if !countCached {
+		countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM %s %s`, src.table(), where)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			countErr = s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
+			if countErr == nil {
+				_ = s.cache.Set(countCacheKey, totalCount, 1*time.Hour)
+			}
+		}()
+	}
+
+	rows, err := s.db.QueryContext(ctx, dataQuery, dataArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("search query failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var keys [][]string
+	for rows.Next() {
+		key := make([]string, len(keyColumns))
+		dest := make([]any, len(key))
+		for i := range key {
+			dest[i] = &key[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("search scan failed: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	hasMore := len(keys) > page.Limit
+	if hasMore {
+		keys = keys[:page.Limit]
+	}
+	if order == "DESC" {
+		slices.Reverse(keys)
+	}
+
+	entityNumbers := make([]string, len(keys))
+	for i, key := range keys {
+		entityNumbers[i] = key[len(key)-1]
+	}
+
+	companies, err := s.enrichCompleteCompanyData(ctx, entityNumbers, criteria.NaceCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if !countCached {
+		wg.Wait()
+		if countErr != nil {
+			return nil, fmt.Errorf("count query failed: %w", countErr)
+		}
+	}
+
+	var meta models.Meta
+	if cursor == nil {
+		meta = buildPageMeta(len(companies), totalCount, page.Limit, offset)
+	} else {
+		meta = models.Meta{Count: len(companies), Total: totalCount, Limit: page.Limit}
+	}
+
+	if len(keys) > 0 {
+		first := keys[0]
+		last := keys[len(keys)-1]
+
+		switch {
+		case cursor == nil:
+			if hasMore {
+				meta.NextCursor = encodeCursor(CURSOR_NEXT, last...)
+			}
+			if offset > 0 {
+				meta.PrevCursor = encodeCursor(CURSOR_PREV, first...)
+			}
+		case cursor.Direction == CURSOR_PREV:
+			meta.NextCursor = encodeCursor(CURSOR_NEXT, last...)
+			if hasMore {
+				meta.PrevCursor = encodeCursor(CURSOR_PREV, first...)
+			}
+		default:
+			if hasMore {
+				meta.NextCursor = encodeCursor(CURSOR_NEXT, last...)
+			}
+			meta.PrevCursor = encodeCursor(CURSOR_PREV, first...)
+		}
+	}
+
+	result := &models.CompanySearchResult{
+		Criteria: criteria,
+		Results:  companies,
+		Meta:     meta,
+	}
+
+	if err := s.cache.Set(pageCacheKey, result, 1*time.Hour); err != nil {
+		slog.Error("Cache write failed", "key", pageCacheKey, "error", err.Error())
+	}
+
+	return result, nil
+}
@@ -4,92 +4,29 @@ import (
 	"context"
 	"csv-importer/api/models"
 	"fmt"
-	"log/slog"
-	"time"
 )
 
-func (s *companyService) SearchByDenomination(ctx context.Context, query string, limit int) (*models.CompanySearchResult, error) {
+// SearchByDenomination returns enterprises with a denomination containing
+// query, case-insensitively. The total comes from a separate count query and
+// only the requested page is enriched.
+func (s *companyService) SearchByDenomination(ctx context.Context, query string, page models.PageRequest) (*models.CompanySearchResult, error) {
 	if query == "" {
 		return nil, fmt.Errorf("denomination query cannot be empty")
 	}
 
-	if limit <= 0 {
-		limit = 50
+	if page.Limit <= 0 {
+		page.Limit = 50
 	}
 
-	cacheKey := fmt.Sprintf("companies:full:denomination:%s", query)
-
-	start := time.Now()
-	var allCompanies []models.CompanyResult
-	err := s.cache.Get(cacheKey, &allCompanies)
-	cacheDuration := time.Since(start)
-
-	if err != nil {
-		slog.Info("Cache miss, fetching by denomination from database",
-			"query", query,
-			"cache_duration_ms", cacheDuration.Milliseconds())
-
-		entityNumbers, err := s.getAllEntityNumbersByDenomination(query)
-		if err != nil {
-			return nil, err
-		}
-
-		if len(entityNumbers) == 0 {
-			return &models.CompanySearchResult{
-				Criteria: models.CompanySearchCriteria{Denomination: query},
-				Results:  []models.CompanyResult{},
-				Meta:     models.Meta{Count: 0, Total: 0, Limit: limit},
-			}, nil
-		}
-
-		if len(entityNumbers) > MAX_COMPANIES {
-			slog.Warn("Dataset too large, truncating",
-				"query", query,
-				"original_count", len(entityNumbers),
-				"truncated_to", MAX_COMPANIES)
-			entityNumbers = entityNumbers[:MAX_COMPANIES]
-		}
-
-		allCompanies, err = s.enrichCompleteCompanyData(entityNumbers, "")
-		if err != nil {
-			return nil, err
-		}
-
-		err = s.cache.Set(cacheKey, allCompanies, 24*time.Hour)
-		if err != nil {
-			slog.Error("Cache write failed", "query", query, "error", err.Error())
-		} else {
-			slog.Info("Cached complete company dataset", "query", query, "total", len(allCompanies))
-		}
-	} else {
-		slog.Info("Cache hit for complete dataset", "query", query, "total", len(allCompanies))
-	}
-
-	return s.buildSearchResult(models.CompanySearchCriteria{Denomination: query}, allCompanies, limit)
-}
-
-func (s *companyService) getAllEntityNumbersByDenomination(query string) ([]string, error) {
-	querySQL := `
-		SELECT DISTINCT entitynumber
-		FROM denomination
-		WHERE denomination ILIKE $1
-		ORDER BY entitynumber
-	`
-
-	rows, err := s.db.Query(querySQL, "%"+query+"%")
+	src, err := s.searchSource(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get entity numbers by denomination: %w", err)
+		return nil, err
 	}
-	defer func() { _ = rows.Close() }()
+	conditions := []string{src.denominationCondition(1)}
+	args := []any{containsPattern(query)}
 
-	var entityNumbers []string
-	for rows.Next() {
-		var entityNumber string
-		if err := rows.Scan(&entityNumber); err == nil {
-			entityNumbers = append(entityNumbers, entityNumber)
-		}
-	}
+	cacheKey := fmt.Sprintf("companies:denomination:%s", query)
+	criteria := models.CompanySearchCriteria{Denomination: query}
 
-	slog.Info("Found entity numbers by denomination", "query", query, "count", len(entityNumbers))
-	return entityNumbers, nil
+	return s.searchCompanies(ctx, src, conditions, args, page, cacheKey, criteria, "")
 }
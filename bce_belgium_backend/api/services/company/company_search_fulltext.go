@@ -0,0 +1,99 @@
+package company
+
+import (
+	"context"
+	"csv-importer/api/models"
+	"fmt"
+	"time"
+)
+
+const MAX_FULLTEXT_LIMIT = 100
+
+// fullTextQuery matches q in French, Dutch and without stemming, the three
+// configurations company_search.search_vector is built with.
+const fullTextQuery = `(websearch_to_tsquery('french', $1) || websearch_to_tsquery('dutch', $1) || websearch_to_tsquery('simple', $1))`
+
+// SearchFullText matches the words of q against the search_vector of
+// company_search (every denomination and the registered municipality) and
+// ranks enterprises with ts_rank. Highlight is a ts_headline snippet with
+// the matched words wrapped in <mark>, in the language that matched.
+func (s *companyService) SearchFullText(ctx context.Context, q string, page models.PageRequest) (*models.CompanySearchResult, error) {
+	limit := min(page.Limit, MAX_FULLTEXT_LIMIT)
+	if limit <= 0 {
+		limit = 20
+	}
+
+	cacheKey := fmt.Sprintf("companies:fts:%s:l%d:o%d", q, limit, page.Offset)
+	var cached models.CompanySearchResult
+	if err := s.cache.Get(cacheKey, &cached); err == nil {
+		return &cached, nil
+	}
+
+	built, err := s.hasCompanySearch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !built {
+		return nil, fmt.Errorf("full-text search needs company_search, run the company-search command")
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		WITH ranked AS (
+			SELECT enterprisenumber, search_vector,
+				concat_ws(' - ', names_fr, names_nl, names_other, municipalityfr, NULLIF(municipalitynl, municipalityfr)) AS text,
+				ts_rank(search_vector, %[1]s)::float8 AS rank,
+				COUNT(*) OVER () AS total
+			FROM company_search
+			WHERE search_vector @@ %[1]s
+			ORDER BY rank DESC, status = 'AC' DESC, enterprisenumber
+			LIMIT $2 OFFSET $3
+		)
+		SELECT enterprisenumber, round(rank::numeric, 4)::float8, total,
+			CASE
+				WHEN search_vector @@ websearch_to_tsquery('french', $1) THEN ts_headline('french', text, websearch_to_tsquery('french', $1), $4)
+				WHEN search_vector @@ websearch_to_tsquery('dutch', $1) THEN ts_headline('dutch', text, websearch_to_tsquery('dutch', $1), $4)
+				ELSE ts_headline('simple', text, websearch_to_tsquery('simple', $1), $4)
+			END
+		FROM ranked
+		ORDER BY rank DESC, enterprisenumber`, fullTextQuery),
+		q, limit, page.Offset, "StartSel=<mark>, StopSel=</mark>, HighlightAll=true")
+	if err != nil {
+		return nil, fmt.Errorf("full-text search failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var entityNumbers []string
+	scores := make(map[string]float64)
+	highlights := make(map[string]string)
+	total := 0
+	for rows.Next() {
+		var entityNumber, highlight string
+		var score float64
+		if err := rows.Scan(&entityNumber, &score, &total, &highlight); err != nil {
+			return nil, fmt.Errorf("full-text search scan failed: %w", err)
+		}
+		entityNumbers = append(entityNumbers, entityNumber)
+		scores[entityNumber] = score
+		highlights[entityNumber] = highlight
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	companies, err := s.enrichCompleteCompanyData(ctx, entityNumbers, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range companies {
+		companies[i].Score = scores[companies[i].EntityNumber]
+		companies[i].Highlight = highlights[companies[i].EntityNumber]
+	}
+
+	result := &models.CompanySearchResult{
+		Criteria: models.CompanySearchCriteria{Query: q},
+		Results:  companies,
+		Meta:     buildPageMeta(len(companies), total, limit, page.Offset),
+	}
+	_ = s.cache.Set(cacheKey, result, 1*time.Hour)
+	return result, nil
+}
@@ -0,0 +1,110 @@
+package company
+
+import (
+	"context"
+	"csv-importer/api/models"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const MAX_NAME_SEARCH_LIMIT = 100
+
+// legalForms are dropped from the query and from the denominations before
+// they are compared, so "Colruyt Group NV" scores like "Colruyt Group".
+var legalForms = []string{
+	"SA", "NV", "SRL", "BV", "SPRL", "BVBA", "SC", "CV", "SCRL", "CVBA", "SCS", "COMMV",
+	"SNC", "VOF", "ASBL", "VZW", "AISBL", "IVZW", "SE",
+}
+
+var legalFormPattern = `\m(` + strings.Join(legalForms, "|") + `)\M`
+
+// stripLegalForms uppercases a name, drops the dots of "S.R.L." and removes
+// the legal form words. A name made only of legal forms is kept.
+func stripLegalForms(name string) string {
+	name = strings.ToUpper(strings.ReplaceAll(name, ".", ""))
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return strings.ContainsRune(" ,;:()'\"-&/", r)
+	})
+
+	kept := make([]string, 0, len(words))
+	for _, word := range words {
+		if !slices.Contains(legalForms, word) {
+			kept = append(kept, word)
+		}
+	}
+	if len(kept) == 0 {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(kept, " ")
+}
+
+// SearchByName ranks enterprises by their closest denomination, in any
+// language, with the denomination trigram index. The score averages
+// word_similarity, which does not penalize extra words in the denomination,
+// and similarity once legal forms are stripped on both sides, which favors
+// exact names.
+func (s *companyService) SearchByName(ctx context.Context, name string, page models.PageRequest) (*models.CompanySearchResult, error) {
+	query := stripLegalForms(name)
+	if query == "" {
+		return nil, fmt.Errorf("name query cannot be empty")
+	}
+
+	limit := min(page.Limit, MAX_NAME_SEARCH_LIMIT)
+	if limit <= 0 {
+		limit = 20
+	}
+
+	cacheKey := fmt.Sprintf("companies:name:%s:l%d:o%d", query, limit, page.Offset)
+	var cached models.CompanySearchResult
+	if err := s.cache.Get(cacheKey, &cached); err == nil {
+		return &cached, nil
+	}
+
+	stripped := fmt.Sprintf("regexp_replace(replace(upper(d.denomination), '.', ''), '%s', ' ', 'g')", legalFormPattern)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT d.entitynumber,
+			MAX(round(((word_similarity($1, d.denomination) + similarity($1, %s)) / 2)::numeric, 3))::float8 AS score
+		FROM denomination d
+		JOIN enterprise e ON e.enterprisenumber = d.entitynumber
+		WHERE $1 <%% d.denomination
+		GROUP BY d.entitynumber
+		ORDER BY score DESC, d.entitynumber
+		LIMIT $2 OFFSET $3`, stripped), query, limit, page.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("name search failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var entityNumbers []string
+	scores := make(map[string]float64)
+	for rows.Next() {
+		var entityNumber string
+		var score float64
+		if err := rows.Scan(&entityNumber, &score); err != nil {
+			return nil, fmt.Errorf("name search scan failed: %w", err)
+		}
+		entityNumbers = append(entityNumbers, entityNumber)
+		scores[entityNumber] = score
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	companies, err := s.enrichCompleteCompanyData(ctx, entityNumbers, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range companies {
+		companies[i].Score = scores[companies[i].EntityNumber]
+	}
+
+	result := &models.CompanySearchResult{
+		Criteria: models.CompanySearchCriteria{Denomination: name},
+		Results:  companies,
+		Meta:     buildPageMeta(len(companies), 0, limit, page.Offset),
+	}
+	_ = s.cache.Set(cacheKey, result, 1*time.Hour)
+	return result, nil
+}
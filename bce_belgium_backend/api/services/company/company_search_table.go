@@ -0,0 +1,151 @@
+package company
+
+import (
+	"context"
+	"csv-importer/api/models"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const COMPANY_SEARCH_BATCH_SIZE = 5000
+
+// searchSource is the table searchCompanies filters and pages on, aliased e
+// so the status and startdate conditions read the same on both:
+// company_search once built, enterprise and its detail tables before. Both
+// apply the same rules, so a search returns the same companies either way.
+type searchSource struct {
+	built bool
+}
+
+func (s *companyService) searchSource(ctx context.Context) (searchSource, error) {
+	built, err := s.hasCompanySearch(ctx)
+	return searchSource{built: built}, err
+}
+
+func (src searchSource) table() string {
+	if src.built {
+		return "company_search e"
+	}
+	return "enterprise e"
+}
+
+// naceCondition matches the main NACE code of the latest nomenclature, as
+// company_search.nacecode keeps it.
+func (src searchSource) naceCondition(arg int) string {
+	if src.built {
+		return fmt.Sprintf("e.nacecode = $%d", arg)
+	}
+	return fmt.Sprintf(`(
+		SELECT a.nacecode FROM activity a
+		WHERE a.entitynumber = e.enterprisenumber AND a.classification = 'MAIN'
+		ORDER BY a.naceversion DESC, a.activitygroup
+		LIMIT 1
+	) = $%d`, arg)
+}
+
+// denominationCondition matches any denomination, in any language, against
+// a pattern from containsPattern. company_search.names joins them with a
+// newline, which the pattern cannot contain, so a match never spans two.
+func (src searchSource) denominationCondition(arg int) string {
+	if src.built {
+		return fmt.Sprintf("e.names ILIKE $%d", arg)
+	}
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM denomination d
+		WHERE d.entitynumber = e.enterprisenumber
+		  AND d.denomination ILIKE $%d
+	)`, arg)
+}
+
+// zipcodeCondition matches the zipcode of the registered address (REGO).
+func (src searchSource) zipcodeCondition(arg int) string {
+	if src.built {
+		return fmt.Sprintf("e.zipcode = $%d", arg)
+	}
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM address ad
+		WHERE ad.entitynumber = e.enterprisenumber
+		  AND ad.zipcode = $%d AND ad.typeofaddress = 'REGO'
+	)`, arg)
+}
+
+// containsPattern is the ILIKE pattern of a substring search: LIKE wildcards
+// typed by the user are escaped and newlines become spaces.
+func containsPattern(query string) string {
+	query = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "\n", " ").Replace(query)
+	return "%" + query + "%"
+}
+
+// loadCompanySearch reads one row per enterprise from company_search, built
+// after each import from the enterprise, denomination, address, contact,
+// activity and establishment tables.
+func (s *companyService) loadCompanySearch(entityNumbers []string, naceCode string) ([]models.CompanyResult, error) {
+	companyMap := make(map[string]*models.CompanyResult, len(entityNumbers))
+
+	for i := 0; i < len(entityNumbers); i += COMPANY_SEARCH_BATCH_SIZE {
+		end := min(i+COMPANY_SEARCH_BATCH_SIZE, len(entityNumbers))
+
+		rows, err := s.db.Query(`
+			SELECT enterprisenumber, COALESCE(status, ''), COALESCE(juridicalform, ''),
+				COALESCE(TO_CHAR(startdate, 'DD-MM-YYYY'), ''), COALESCE(denomination, ''),
+				COALESCE(zipcode, ''), COALESCE(municipalityfr, ''), COALESCE(streetfr, ''), COALESCE(housenumber, ''),
+				COALESCE(nacecode, ''), COALESCE(email, ''), COALESCE(web, ''), COALESCE(tel, ''), COALESCE(fax, ''),
+				denominations, addresses, contacts, activities, establishments
+			FROM company_search
+			WHERE enterprisenumber = ANY($1)`, entityNumbers[i:end])
+		if err != nil {
+			return nil, fmt.Errorf("company_search query failed: %w", err)
+		}
+
+		for rows.Next() {
+			var c models.CompanyResult
+			var denominations, addresses, contacts, activities, establishments []byte
+			if err := rows.Scan(&c.EntityNumber, &c.Status, &c.JuridicalForm, &c.StartDate, &c.Denomination,
+				&c.ZipCode, &c.City, &c.Street, &c.HouseNumber, &c.NaceCode, &c.Email, &c.Website, &c.Phone, &c.Fax,
+				&denominations, &addresses, &contacts, &activities, &establishments); err != nil {
+				_ = rows.Close()
+				return nil, fmt.Errorf("company_search scan failed: %w", err)
+			}
+
+			c.Denominations = decodeDetails(denominations)
+			c.Addresses = decodeDetails(addresses)
+			c.Contacts = decodeDetails(contacts)
+			c.Activities = decodeDetails(activities)
+			c.Establishments = decodeDetails(establishments)
+			c.Enterprise = map[string]any{
+				"status":         c.Status,
+				"juridical_form": c.JuridicalForm,
+				"start_date":     c.StartDate,
+			}
+			if naceCode != "" {
+				c.NaceCode = naceCode
+			}
+			companyMap[c.EntityNumber] = &c
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("rows error: %w", err)
+		}
+	}
+
+	results := make([]models.CompanyResult, 0, len(entityNumbers))
+	for _, entityNumber := range entityNumbers {
+		if company, exists := companyMap[entityNumber]; exists {
+			results = append(results, *company)
+		}
+	}
+
+	slog.Info("Loaded companies from company_search", "requested", len(entityNumbers), "found", len(results))
+	return results, nil
+}
+
+func decodeDetails(data []byte) []map[string]any {
+	var details []map[string]any
+	if err := json.Unmarshal(data, &details); err != nil || len(details) == 0 {
+		return nil
+	}
+	return details
+}
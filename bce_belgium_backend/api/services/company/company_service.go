@@ -1,17 +1,22 @@
 package company
 
 import (
+	"context"
 	"csv-importer/api/cache"
 	"database/sql"
+	"fmt"
 	"log/slog"
 	"os"
+	"sync/atomic"
 )
 
-const MAX_COMPANIES = 100000
-
 type companyService struct {
 	db    *sql.DB
 	cache *cache.RedisCache
+
+	// searchBuilt is set once company_search has been seen. The table is
+	// only replaced after that, never dropped, so it is not checked again.
+	searchBuilt atomic.Bool
 }
 
 func NewCompanyService(db *sql.DB) CompanyService {
@@ -32,3 +37,21 @@ func NewCompanyService(db *sql.DB) CompanyService {
 		cache: redisCache,
 	}
 }
+
+// hasCompanySearch reports whether company_search exists. Until it does,
+// every call checks again, so the first company-search build is picked up
+// without restarting the API.
+func (s *companyService) hasCompanySearch(ctx context.Context) (bool, error) {
+	if s.searchBuilt.Load() {
+		return true, nil
+	}
+
+	var built bool
+	if err := s.db.QueryRowContext(ctx, "SELECT to_regclass('company_search') IS NOT NULL").Scan(&built); err != nil {
+		return false, fmt.Errorf("check company_search: %w", err)
+	}
+	if built {
+		s.searchBuilt.Store(true)
+	}
+	return built, nil
+}
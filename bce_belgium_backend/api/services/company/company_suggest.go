@@ -0,0 +1,65 @@
+package company
+
+import (
+	"context"
+	"csv-importer/api/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	SUGGEST_LIMIT      = 10
+	MIN_SUGGEST_LENGTH = 2
+)
+
+// Suggest returns the first active enterprises whose main denomination
+// starts with prefix, case ignored, with their registered municipality.
+// idx_company_search_suggest keeps upper(denomination) in byte order ("C"
+// collation), so a prefix is one index range read up to the limit. Results
+// are cached per prefix.
+func (s *companyService) Suggest(ctx context.Context, prefix string) ([]models.Suggestion, error) {
+	prefix = strings.ToUpper(strings.Join(strings.Fields(prefix), " "))
+
+	cacheKey := fmt.Sprintf("companies:suggest:%s", prefix)
+	var cached []models.Suggestion
+	if err := s.cache.Get(cacheKey, &cached); err == nil {
+		return cached, nil
+	}
+
+	built, err := s.hasCompanySearch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !built {
+		return nil, fmt.Errorf("suggestions need company_search, run the company-search command")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT enterprisenumber, denomination, COALESCE(zipcode, ''), COALESCE(municipalityfr, municipalitynl, '')
+		FROM company_search
+		WHERE upper(denomination) COLLATE "C" >= $1
+			AND upper(denomination) COLLATE "C" < $1 || chr(1114111)
+			AND status = 'AC'
+		ORDER BY upper(denomination) COLLATE "C", enterprisenumber
+		LIMIT $2`, prefix, SUGGEST_LIMIT)
+	if err != nil {
+		return nil, fmt.Errorf("suggest failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	suggestions := make([]models.Suggestion, 0, SUGGEST_LIMIT)
+	for rows.Next() {
+		var sg models.Suggestion
+		if err := rows.Scan(&sg.EntityNumber, &sg.Denomination, &sg.ZipCode, &sg.City); err != nil {
+			return nil, fmt.Errorf("suggest scan failed: %w", err)
+		}
+		suggestions = append(suggestions, sg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	_ = s.cache.Set(cacheKey, suggestions, 1*time.Hour)
+	return suggestions, nil
+}
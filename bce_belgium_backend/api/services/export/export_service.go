@@ -85,7 +85,7 @@ func (s *exportService) buildExportQuery(opts ExportOptions, columns []string) (
 		query = fmt.Sprintf(`
 			SELECT %s
 			FROM %s
-			WHERE %s ILIKE $1
+			WHERE %s::text ILIKE $1
 			LIMIT $2
 		`, strings.Join(columns, ","), opts.TableName, opts.ColumnName)
 		args = []any{"%" + opts.SearchValue + "%", opts.Limit}
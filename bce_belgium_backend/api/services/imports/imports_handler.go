@@ -0,0 +1,43 @@
+package imports
+
+import (
+	"csv-importer/api/middleware"
+	"csv-importer/api/models"
+	"log/slog"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	importService ImportService
+}
+
+func NewHandler(importService ImportService) *Handler {
+	if importService == nil {
+		slog.Error("importService is nil")
+		os.Exit(1)
+	}
+
+	return &Handler{
+		importService: importService,
+	}
+}
+
+func (h *Handler) ListImports() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		responseHelper := middleware.GetResponseHelper(c)
+		params := middleware.GetParsedParams(c)
+
+		runs, err := h.importService.ListRuns(c.Request.Context(), params.Limit)
+		if err != nil {
+			slog.Error("failed to list imports",
+				slog.String("error", err.Error()),
+			)
+			responseHelper.Error("failed to get imports: "+err.Error(), 500)
+			return
+		}
+
+		responseHelper.SuccessWithMeta(runs, models.Meta{Count: len(runs), Limit: params.Limit})
+	}
+}
@@ -0,0 +1,10 @@
+package imports
+
+import (
+	"context"
+	"csv-importer/api/models"
+)
+
+type ImportService interface {
+	ListRuns(ctx context.Context, limit int) ([]models.ImportRun, error)
+}
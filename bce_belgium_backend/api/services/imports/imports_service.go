@@ -0,0 +1,92 @@
+package imports
+
+import (
+	"context"
+	"csv-importer/api/models"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+type importService struct {
+	db *sql.DB
+}
+
+func NewImportService(db *sql.DB) ImportService {
+	if db == nil {
+		slog.Error("database connection is nil")
+		os.Exit(1)
+	}
+
+	return &importService{
+		db: db,
+	}
+}
+
+func (s *importService) ListRuns(ctx context.Context, limit int) ([]models.ImportRun, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, "SELECT to_regclass('import_runs') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check import catalog: %w", err)
+	}
+	if !exists {
+		return []models.ImportRun{}, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, source, status, rows, rejects, started_at, finished_at,
+			COALESCE(EXTRACT(EPOCH FROM (COALESCE(finished_at, now()) - started_at)) * 1000, 0)::bigint
+		FROM import_runs
+		ORDER BY id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import runs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	runs := []models.ImportRun{}
+	index := make(map[int64]int)
+	var ids []int64
+	for rows.Next() {
+		var r models.ImportRun
+		if err := rows.Scan(&r.ID, &r.Source, &r.Status, &r.Rows, &r.Rejects, &r.StartedAt, &r.FinishedAt, &r.DurationMs); err != nil {
+			return nil, err
+		}
+		r.Files = []models.ImportFile{}
+		index[r.ID] = len(runs)
+		ids = append(ids, r.ID)
+		runs = append(runs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return runs, nil
+	}
+
+	fileRows, err := s.db.QueryContext(ctx, `
+		SELECT run_id, file_name, table_name, status, COALESCE(checksum, ''),
+			COALESCE(to_char(extract_date, 'YYYY-MM-DD'), ''), rows, rejects, started_at, finished_at,
+			COALESCE(EXTRACT(EPOCH FROM (COALESCE(finished_at, updated_at) - started_at)) * 1000, 0)::bigint
+		FROM import_files
+		WHERE run_id = ANY($1)
+		ORDER BY run_id DESC, started_at`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import files: %w", err)
+	}
+	defer func() { _ = fileRows.Close() }()
+
+	for fileRows.Next() {
+		var runID int64
+		var f models.ImportFile
+		if err := fileRows.Scan(&runID, &f.FileName, &f.TableName, &f.Status, &f.Checksum,
+			&f.ExtractDate, &f.Rows, &f.Rejects, &f.StartedAt, &f.FinishedAt, &f.DurationMs); err != nil {
+			return nil, err
+		}
+		run := &runs[index[runID]]
+		run.Files = append(run.Files, f)
+	}
+
+	return runs, fileRows.Err()
+}
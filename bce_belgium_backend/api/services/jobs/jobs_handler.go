@@ -0,0 +1,134 @@
+package jobs
+
+import (
+	"csv-importer/api/models"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	jobService JobService
+}
+
+func NewHandler(jobService JobService) *Handler {
+	if jobService == nil {
+		slog.Error("jobService is nil")
+		os.Exit(1)
+	}
+
+	return &Handler{
+		jobService: jobService,
+	}
+}
+
+func parseJobID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(400, models.Error("job id must be a positive integer"))
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *Handler) SubmitEnrichJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(400, models.Error("multipart field 'file' with a CSV is required"))
+			return
+		}
+		defer func() { _ = file.Close() }()
+
+		tooLarge := fmt.Sprintf("file larger than %d MB", MAX_JOB_FILE_SIZE>>20)
+		if header.Size > MAX_JOB_FILE_SIZE {
+			c.JSON(413, models.Error(tooLarge))
+			return
+		}
+		input, err := io.ReadAll(io.LimitReader(file, MAX_JOB_FILE_SIZE+1))
+		if err != nil {
+			c.JSON(400, models.Error("failed to read file: "+err.Error()))
+			return
+		}
+		if len(input) > MAX_JOB_FILE_SIZE {
+			c.JSON(413, models.Error(tooLarge))
+			return
+		}
+
+		columns := models.EnrichJobColumns{
+			Identifier: c.PostForm("identifier_column"),
+			Name:       c.PostForm("name_column"),
+			ZipCode:    c.PostForm("zipcode_column"),
+		}
+		job, err := h.jobService.Submit(c.Request.Context(), filepath.Base(header.Filename), input, columns)
+		if errors.Is(err, ErrInvalidJobFile) {
+			c.JSON(400, models.Error(err.Error()))
+			return
+		}
+		if err != nil {
+			slog.Error("failed to submit enrich job",
+				slog.String("file", header.Filename),
+				slog.String("error", err.Error()),
+			)
+			c.JSON(500, models.Error("submit failed: "+err.Error()))
+			return
+		}
+
+		c.JSON(202, models.Success(job))
+	}
+}
+
+func (h *Handler) GetJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseJobID(c)
+		if !ok {
+			return
+		}
+
+		job, err := h.jobService.GetJob(c.Request.Context(), id)
+		if errors.Is(err, ErrJobNotFound) {
+			c.JSON(404, models.Error(fmt.Sprintf("job %d not found", id)))
+			return
+		}
+		if err != nil {
+			c.JSON(500, models.Error(err.Error()))
+			return
+		}
+
+		c.JSON(200, models.Success(job))
+	}
+}
+
+func (h *Handler) GetJobResult() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseJobID(c)
+		if !ok {
+			return
+		}
+
+		job, result, err := h.jobService.GetResult(c.Request.Context(), id)
+		if errors.Is(err, ErrJobNotFound) {
+			c.JSON(404, models.Error(fmt.Sprintf("job %d not found", id)))
+			return
+		}
+		if err != nil {
+			c.JSON(500, models.Error(err.Error()))
+			return
+		}
+		if job.Status != JOB_COMPLETED {
+			c.JSON(409, models.Error(fmt.Sprintf("job %d is %s, no result yet", id, job.Status)))
+			return
+		}
+
+		name := strings.TrimSuffix(job.FileName, filepath.Ext(job.FileName)) + "_enriched.csv"
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		c.Data(200, "text/csv; charset=utf-8", result)
+	}
+}
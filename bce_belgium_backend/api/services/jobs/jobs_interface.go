@@ -0,0 +1,13 @@
+package jobs
+
+import (
+	"context"
+	"csv-importer/api/models"
+)
+
+type JobService interface {
+	Submit(ctx context.Context, fileName string, input []byte, columns models.EnrichJobColumns) (*models.EnrichJob, error)
+	GetJob(ctx context.Context, id int64) (*models.EnrichJob, error)
+	GetResult(ctx context.Context, id int64) (*models.EnrichJob, []byte, error)
+	Run(ctx context.Context)
+}
@@ -0,0 +1,248 @@
+package jobs
+
+import (
+	"context"
+	"csv-importer/api/models"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	MATCH_FOUND     = "matched"
+	MATCH_NOT_FOUND = "not_found"
+	MATCH_INVALID   = "invalid_identifier"
+
+	MATCH_BY_IDENTIFIER = "identifier"
+	MATCH_BY_NAME       = "name_zipcode"
+)
+
+// Header names recognized when the job does not name its columns.
+var (
+	identifierHeaders = []string{"enterprisenumber", "enterprise_number", "establishmentnumber", "establishment_number",
+		"entitynumber", "vat", "tva", "btw", "kbo", "bce", "identifier"}
+	nameHeaders    = []string{"denomination", "name", "company", "naam", "nom"}
+	zipCodeHeaders = []string{"zipcode", "zip", "postal_code", "postcode", "code_postal"}
+)
+
+// enrichColumns are appended to every row of the result.
+var enrichColumns = []string{
+	"match_status", "match_method", "match_confidence",
+	"bce_enterprisenumber", "bce_denomination", "bce_status", "bce_juridical_form", "bce_start_date",
+	"bce_nace_code", "bce_street", "bce_house_number", "bce_zipcode", "bce_city",
+	"bce_email", "bce_web", "bce_tel",
+}
+
+type matchResult struct {
+	status     string
+	method     string
+	confidence float64
+	company    *models.CompanyResult
+}
+
+func (m matchResult) fields() []string {
+	fields := make([]string, len(enrichColumns))
+	fields[0] = m.status
+	if m.company == nil {
+		return fields
+	}
+
+	c := m.company
+	copy(fields[1:], []string{
+		m.method, strconv.FormatFloat(m.confidence, 'f', 2, 64),
+		c.EntityNumber, c.Denomination, c.Status, c.JuridicalForm, c.StartDate,
+		c.NaceCode, c.Street, c.HouseNumber, c.ZipCode, c.City,
+		c.Email, c.Website, c.Phone,
+	})
+	return fields
+}
+
+// resolveColumns checks the requested columns against the header, or guesses
+// them. A job needs an identifier column or a name and a zipcode column.
+func resolveColumns(header []string, columns models.EnrichJobColumns) (models.EnrichJobColumns, error) {
+	cleaned := make([]string, len(header))
+	for i, h := range header {
+		cleaned[i] = cleanHeader(h)
+	}
+
+	resolve := func(requested string, candidates []string) (string, error) {
+		if requested != "" {
+			if !slices.Contains(cleaned, cleanHeader(requested)) {
+				return "", fmt.Errorf("%w: column %q not found in header", ErrInvalidJobFile, requested)
+			}
+			return cleanHeader(requested), nil
+		}
+		for _, candidate := range candidates {
+			if slices.Contains(cleaned, candidate) {
+				return candidate, nil
+			}
+		}
+		return "", nil
+	}
+
+	var err error
+	if columns.Identifier, err = resolve(columns.Identifier, identifierHeaders); err != nil {
+		return columns, err
+	}
+	if columns.Name, err = resolve(columns.Name, nameHeaders); err != nil {
+		return columns, err
+	}
+	if columns.ZipCode, err = resolve(columns.ZipCode, zipCodeHeaders); err != nil {
+		return columns, err
+	}
+
+	if columns.Identifier == "" && (columns.Name == "" || columns.ZipCode == "") {
+		return columns, fmt.Errorf("%w: needs an enterprise/establishment number column or a name and a zipcode column", ErrInvalidJobFile)
+	}
+	return columns, nil
+}
+
+type columnIndex struct {
+	identifier, name, zipCode int
+}
+
+func columnIndexes(header []string, columns models.EnrichJobColumns) columnIndex {
+	index := columnIndex{identifier: -1, name: -1, zipCode: -1}
+	for i, h := range header {
+		switch h = cleanHeader(h); {
+		case columns.Identifier != "" && h == columns.Identifier && index.identifier < 0:
+			index.identifier = i
+		case columns.Name != "" && h == columns.Name && index.name < 0:
+			index.name = i
+		case columns.ZipCode != "" && h == columns.ZipCode && index.zipCode < 0:
+			index.zipCode = i
+		}
+	}
+	return index
+}
+
+func field(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// matchChunk matches rows by enterprise or establishment number first, then
+// the rows left by name and zipcode, with a fixed number of queries per chunk.
+func (s *jobService) matchChunk(ctx context.Context, rows [][]string, index columnIndex) ([]matchResult, error) {
+	matches := make([]matchResult, len(rows))
+	for i := range matches {
+		matches[i].status = MATCH_NOT_FOUND
+	}
+
+	var identifiers []string
+	var identifierRows []int
+	for i, row := range rows {
+		if value := field(row, index.identifier); value != "" {
+			identifiers = append(identifiers, value)
+			identifierRows = append(identifierRows, i)
+		}
+	}
+
+	if len(identifiers) > 0 {
+		results, err := s.companyService.LookupIdentifiers(ctx, identifiers)
+		if err != nil {
+			return nil, err
+		}
+		for j, result := range results {
+			m := &matches[identifierRows[j]]
+			switch result.Status {
+			case models.LOOKUP_FOUND:
+				*m = matchResult{status: MATCH_FOUND, method: MATCH_BY_IDENTIFIER, confidence: 1, company: result.Company}
+			case models.LOOKUP_INVALID:
+				m.status = MATCH_INVALID
+			}
+		}
+	}
+
+	var names, zipCodes []string
+	var nameRows []int
+	for i, row := range rows {
+		if matches[i].status == MATCH_FOUND {
+			continue
+		}
+		name, zipCode := field(row, index.name), field(row, index.zipCode)
+		if name != "" && zipCode != "" {
+			names = append(names, name)
+			zipCodes = append(zipCodes, zipCode)
+			nameRows = append(nameRows, i)
+		}
+	}
+	if len(names) == 0 {
+		return matches, nil
+	}
+
+	candidates, err := s.matchNames(ctx, names, zipCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	var enterpriseNumbers []string
+	var enterpriseRows []int
+	for j, candidate := range candidates {
+		if candidate.enterpriseNumber != "" {
+			enterpriseNumbers = append(enterpriseNumbers, candidate.enterpriseNumber)
+			enterpriseRows = append(enterpriseRows, j)
+		}
+	}
+	if len(enterpriseNumbers) == 0 {
+		return matches, nil
+	}
+
+	results, err := s.companyService.LookupIdentifiers(ctx, enterpriseNumbers)
+	if err != nil {
+		return nil, err
+	}
+	for k, result := range results {
+		if result.Status != models.LOOKUP_FOUND {
+			continue
+		}
+		j := enterpriseRows[k]
+		matches[nameRows[j]] = matchResult{
+			status: MATCH_FOUND, method: MATCH_BY_NAME, confidence: candidates[j].score, company: result.Company,
+		}
+	}
+	return matches, nil
+}
+
+type nameCandidate struct {
+	enterpriseNumber string
+	score            float64
+}
+
+// matchNames finds, for each name and zipcode pair, the enterprise registered
+// (REGO address) in that zipcode whose denomination, in any language, is the
+// closest trigram match.
+func (s *jobService) matchNames(ctx context.Context, names, zipCodes []string) ([]nameCandidate, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT q.n, m.enterprisenumber, m.score
+		FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS q(name, zipcode, n)
+		CROSS JOIN LATERAL (
+			SELECT e.enterprisenumber, similarity(d.denomination, q.name) AS score
+			FROM address a
+			JOIN enterprise e ON e.enterprisenumber = a.entitynumber
+			JOIN denomination d ON d.entitynumber = a.entitynumber
+			WHERE a.typeofaddress = 'REGO'
+				AND a.zipcode = q.zipcode
+				AND d.denomination % q.name
+			ORDER BY score DESC, e.status = 'AC' DESC
+			LIMIT 1
+		) m`, names, zipCodes)
+	if err != nil {
+		return nil, fmt.Errorf("name match failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	candidates := make([]nameCandidate, len(names))
+	for rows.Next() {
+		var n int
+		var candidate nameCandidate
+		if err := rows.Scan(&n, &candidate.enterpriseNumber, &candidate.score); err != nil {
+			return nil, fmt.Errorf("name match scan failed: %w", err)
+		}
+		candidates[n-1] = candidate
+	}
+	return candidates, rows.Err()
+}
@@ -47,7 +47,7 @@ func (s *searchService) SearchInColumn(ctx context.Context, tableName, columnNam
 	query := fmt.Sprintf(`
 		SELECT DISTINCT %s
 		FROM %s
-		WHERE %s ILIKE $1
+		WHERE %s::text ILIKE $1
 		ORDER BY %s
 		LIMIT $2
 	`, columnName, tableName, columnName, columnName)
@@ -97,7 +97,7 @@ func (s *searchService) CountMatches(ctx context.Context, tableName, columnName,
 	query := fmt.Sprintf(`
 		SELECT COUNT(*)
 		FROM %s
-		WHERE %s ILIKE $1
+		WHERE %s::text ILIKE $1
 	`, tableName, columnName)
 
 	var count int64
@@ -136,7 +136,7 @@ func (s *searchService) SearchMultipleColumns(ctx context.Context, tableName str
 	// Build WHERE clause for multiple columns
 	var whereConditions []string
 	for _, col := range columns {
-		whereConditions = append(whereConditions, fmt.Sprintf("%s ILIKE $1", col))
+		whereConditions = append(whereConditions, fmt.Sprintf("%s::text ILIKE $1", col))
 	}
 
 	query := fmt.Sprintf(`
@@ -31,7 +31,17 @@ func (c *CLI) Execute(args []string) {
 	case "api":
 		handlers.HandleAPI()
 	case "all":
-		handlers.HandleImportAll(c.db)
+		handlers.HandleImportAll(c.db, args[2:])
+	case "update":
+		handlers.HandleUpdate(c.db, args[2:])
+	case "rollback":
+		handlers.HandleRollback(c.db)
+	case "company-search":
+		handlers.HandleBuildCompanySearch(c.db)
+	case "indexes":
+		handlers.HandleCreateIndexes(c.db)
+	case "imports":
+		handlers.HandleListImports(c.db)
 	case "test-redis":
 		handlers.HandleTestRedis(c.db)
 	case "list":
@@ -12,7 +12,12 @@ USAGE:
 COMMANDS:
   📊 DATABASE OPERATIONS:
     api                              Launch API server
-    all                             Import all CSV files in parallel
+    all [--resume]                  Import all CSV files in parallel (--resume continues the last interrupted import)
+    update [dir]                    Apply BCE update extracts (*_delete.csv / *_insert.csv)
+    rollback                        Swap live tables with their *__previous generation
+    company-search                  Rebuild the company_search table (runs after all, update and rollback)
+    indexes                         Create the search indexes on live tables (btree + trigram)
+    imports                         Show the last import runs (files, checksums, extract dates, rows, rejects)
     list                            List available CSV files
 
   📋 TABLE MANAGEMENT:
@@ -37,6 +42,7 @@ COMMANDS:
 EXAMPLES:
   go run main.go api                                    # Start API server
   go run main.go all                                    # Import all CSVs
+  go run main.go update ../bce_update                   # Apply next update extract
   go run main.go search activity nacecode 62020 100    # Find companies in IT sector
   go run main.go export activity nacecode 62020 it.csv # Export IT companies
   go run main.go preview denomination 10                # Preview company names
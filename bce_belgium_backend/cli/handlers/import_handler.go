@@ -4,12 +4,42 @@ import (
 	"csv-importer/csv"
 	"database/sql"
 	"log/slog"
+	"os"
+	"slices"
 )
 
-func HandleImportAll(db *sql.DB) {
+func HandleImportAll(db *sql.DB, args []string) {
 	csvDir := "../bce_mai_2025"
-	if err := csv.ProcessAllCSVsParallel(db, csvDir); err != nil {
+	resume := slices.Contains(args, "--resume")
+	if err := csv.ProcessAllCSVsParallel(db, csvDir, resume); err != nil {
 		slog.Error("❌ Parallel batch processing failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func HandleUpdate(db *sql.DB, args []string) {
+	updateDir := "../bce_update"
+	if len(args) > 0 {
+		updateDir = args[0]
+	}
+
+	if err := csv.ProcessAllUpdates(db, updateDir); err != nil {
+		slog.Error("❌ Update failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func HandleRollback(db *sql.DB) {
+	if err := csv.RollbackTables(db); err != nil {
+		slog.Error("❌ Rollback failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func HandleBuildCompanySearch(db *sql.DB) {
+	if err := csv.BuildCompanySearch(db); err != nil {
+		slog.Error("❌ company_search build failed", "error", err)
+		os.Exit(1)
 	}
 }
 
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"csv-importer/api/services/imports"
+	"database/sql"
+	"fmt"
+)
+
+func HandleListImports(db *sql.DB) {
+	runs, err := imports.NewImportService(db).ListRuns(context.Background(), 10)
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("ℹ️ No import recorded yet")
+		return
+	}
+
+	for _, run := range runs {
+		fmt.Printf("\n📦 #%d %-10s %s  %d rows, %d rejects, %.1f min (%s)\n",
+			run.ID, run.Status, run.StartedAt.Format("2006-01-02 15:04"),
+			run.Rows, run.Rejects, float64(run.DurationMs)/60000, run.Source)
+
+		for _, f := range run.Files {
+			fmt.Printf("   • %-28s %-20s %-8s extract %-10s %10d rows %6d rejects  sha256 %.12s\n",
+				f.FileName, f.TableName, f.Status, f.ExtractDate, f.Rows, f.Rejects, f.Checksum)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"csv-importer/csv"
+	"database/sql"
+	"log/slog"
+	"os"
+)
+
+func HandleCreateIndexes(db *sql.DB) {
+	if err := csv.CreateIndexes(db); err != nil {
+		slog.Error("❌ Index creation failed", "error", err)
+		os.Exit(1)
+	}
+}
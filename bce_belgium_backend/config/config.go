@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +13,9 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBName     string
+
+	ImportMaxRejects int
+	RejectsDir       string
 }
 
 func Load() *Config {
@@ -23,6 +27,9 @@ func Load() *Config {
 		DBUser:     getEnv("POSTGRES_USER", ""),
 		DBPassword: getEnv("POSTGRES_PASSWORD", ""),
 		DBName:     getEnv("POSTGRES_DB", ""),
+
+		ImportMaxRejects: getEnvInt("IMPORT_MAX_REJECTS", 1000),
+		RejectsDir:       getEnv("IMPORT_REJECTS_DIR", "rejects"),
 	}
 }
 
@@ -32,3 +39,10 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -1,7 +1,9 @@
 package csv
 
 import (
+	"csv-importer/config"
 	"database/sql"
+	"encoding/csv"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -46,7 +48,7 @@ func getCSVFiles(csvDir string) ([]CSVFile, error) {
 	return csvFiles, nil
 }
 
-func ProcessAllCSVsParallel(db *sql.DB, csvDir string) error {
+func ProcessAllCSVsParallel(db *sql.DB, csvDir string, resume bool) error {
 	csvFiles, err := getCSVFiles(csvDir)
 	if err != nil {
 		return fmt.Errorf("error scanning CSV directory: %v", err)
@@ -56,18 +58,90 @@ func ProcessAllCSVsParallel(db *sql.DB, csvDir string) error {
 		return fmt.Errorf("no CSV files found in %s", csvDir)
 	}
 
+	run, err := StartImportRun(db, csvDir, resume)
+	if err != nil {
+		return err
+	}
+
+	if err := importCSVs(db, run, csvFiles); err != nil {
+		run.Finish(db, RUN_FAILED)
+		return err
+	}
+
+	run.Finish(db, RUN_COMPLETED)
+	return nil
+}
+
+func importCSVs(db *sql.DB, run *ImportRun, csvFiles []CSVFile) error {
+	// The staging tables of a swapped run are live already: only the steps
+	// after the swap are left.
+	if run.Swapped {
+		fmt.Printf("♻️ Import #%d already swapped in, rebuilding company_search only\n", run.ID)
+		return BuildCompanySearch(db)
+	}
+
 	fmt.Printf("🔥 BLAZINGLY FAST processing %d CSV files\n", len(csvFiles))
 
 	totalStart := time.Now()
 
+	cfg := config.Load()
+	var tables []string
+	loaded := make(map[string]int)
+	rejected := make(map[string]string)
+	totalRejects := 0
+
+	var extractDate *time.Time
+	if len(csvFiles) > 0 {
+		extractDate = snapshotDate(filepath.Dir(csvFiles[0].Path))
+	}
+
 	for i, csvFile := range csvFiles {
+		checksum, err := fileChecksum(csvFile.Path)
+		if err != nil {
+			return fmt.Errorf("checksum %s: %v", csvFile.Name, err)
+		}
+
+		cp, err := run.checkpoint(db, csvFile.Name, csvFile.TableName, checksum, extractDate)
+		if err != nil {
+			return err
+		}
+
+		tables = append(tables, csvFile.TableName)
+
+		if cp.status == FILE_LOADED {
+			loaded[csvFile.TableName] = int(cp.rows)
+			rejected[csvFile.TableName] = fmt.Sprintf("%d rejects (previous attempt)", cp.rejects)
+			totalRejects += cp.rejects
+			fmt.Printf("\n♻️ [%d/%d] %s already loaded (%d rows), skipping\n", i+1, len(csvFiles), csvFile.Name, cp.rows)
+			continue
+		}
+
 		fmt.Printf("\n🔥 [%d/%d] Processing %s BLAZINGLY FAST...\n", i+1, len(csvFiles), csvFile.Name)
 
-		if err := ProcessCSVBlazingFast(db, csvFile.Path, csvFile.TableName); err != nil {
+		stagingTable := csvFile.TableName + STAGING_SUFFIX
+		rejects := NewRejectWriter(cfg.RejectsDir, csvFile.TableName)
+		totalLines, err := ProcessCSVBlazingFast(db, csvFile.Path, stagingTable, rejects, cp)
+		if closeErr := rejects.Close(); closeErr != nil {
+			fmt.Printf("❌ Reject file error: %v\n", closeErr)
+		}
+		if err != nil {
 			return fmt.Errorf("error processing %s: %v", csvFile.Name, err)
 		}
 
-		fmt.Printf("🎉 [%d/%d] %s → table '%s' completed\n", i+1, len(csvFiles), csvFile.Name, csvFile.TableName)
+		loaded[csvFile.TableName] = int(cp.rows) + totalLines
+		// cp.rejects holds the rejects of batches committed by an earlier
+		// attempt, which this run skipped.
+		fileRejects := cp.rejects + rejects.Total()
+		if err := cp.markLoaded(db, int64(loaded[csvFile.TableName]), fileRejects); err != nil {
+			return fmt.Errorf("checkpoint %s: %v", csvFile.Name, err)
+		}
+
+		rejected[csvFile.TableName] = rejects.Summary()
+		if cp.rejects > 0 {
+			rejected[csvFile.TableName] += fmt.Sprintf(" + %d (previous attempt)", cp.rejects)
+		}
+		totalRejects += fileRejects
+		fmt.Printf("🎉 [%d/%d] %s → table '%s' completed (%s)\n", i+1, len(csvFiles), csvFile.Name, stagingTable, rejects.Summary())
 	}
 
 	totalElapsed := time.Since(totalStart)
@@ -75,8 +149,60 @@ func ProcessAllCSVsParallel(db *sql.DB, csvDir string) error {
 
 	fmt.Println("\n📊 SUMMARY:")
 	for _, csvFile := range csvFiles {
-		fmt.Printf("   • %s → table '%s'\n", csvFile.Name, csvFile.TableName)
+		fmt.Printf("   • %s → table '%s' (%d rows, %s)\n", csvFile.Name, csvFile.TableName,
+			loaded[csvFile.TableName], rejected[csvFile.TableName])
 	}
 
+	if err := checkRejectThreshold(totalRejects, cfg.ImportMaxRejects); err != nil {
+		return fmt.Errorf("%v, live tables untouched", err)
+	}
+
+	for _, table := range tables {
+		if err := copyLiveIndexes(db, table); err != nil {
+			return fmt.Errorf("staging indexes failed, live tables untouched: %v", err)
+		}
+	}
+
+	if err := createStagingIndexes(db, tables); err != nil {
+		return fmt.Errorf("staging indexes failed, live tables untouched: %v", err)
+	}
+
+	for _, table := range tables {
+		if err := validateStaging(db, table, loaded[table]); err != nil {
+			return fmt.Errorf("validation failed, live tables untouched: %v", err)
+		}
+	}
+
+	if err := swapTables(db, run, tables); err != nil {
+		return err
+	}
+
+	return BuildCompanySearch(db)
+}
+
+// snapshotDate reads the SnapshotDate (dd-mm-yyyy) from the meta.csv shipped
+// with every KBO open data extract.
+func snapshotDate(csvDir string) *time.Time {
+	file, err := os.Open(filepath.Join(csvDir, "meta.csv"))
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = file.Close() }()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil
+	}
+
+	for _, record := range records {
+		if len(record) < 2 || record[0] != "SnapshotDate" {
+			continue
+		}
+		date, err := time.Parse("02-01-2006", strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil
+		}
+		return &date
+	}
 	return nil
 }
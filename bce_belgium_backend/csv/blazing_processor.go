@@ -11,14 +11,14 @@ import (
 	"time"
 )
 
-func ProcessCSVBlazingFast(db *sql.DB, csvPath, tableName string) error {
+func ProcessCSVBlazingFast(db *sql.DB, csvPath, tableName string, rejects *RejectWriter, cp *fileCheckpoint) (int, error) {
 	start := time.Now()
 
 	fmt.Printf("🔥 ULTRA FAST streaming import\n")
 
 	file, err := os.Open(csvPath)
 	if err != nil {
-		return fmt.Errorf("impossible to open %s: %v", csvPath, err)
+		return 0, fmt.Errorf("impossible to open %s: %v", csvPath, err)
 	}
 	defer func() { _ = file.Close() }()
 
@@ -28,21 +28,28 @@ func ProcessCSVBlazingFast(db *sql.DB, csvPath, tableName string) error {
 
 	headers, err := reader.Read()
 	if err != nil {
-		return fmt.Errorf("error reading header: %v", err)
+		return 0, fmt.Errorf("error reading header: %v", err)
 	}
 
 	fmt.Printf("📄 CSV: %s\n", filepath.Base(csvPath))
 	fmt.Printf("📊 Columns: %v\n", headers)
 
-	cleanHeaders, columns := PrepareHeaders(headers)
+	schema, columns := PrepareHeaders(strings.TrimSuffix(tableName, STAGING_SUFFIX), headers)
 
-	if err := setupTable(db, tableName, columns); err != nil {
-		return err
+	reuse, err := cp.canReuse(db, tableName)
+	if err != nil {
+		return 0, err
+	}
+
+	if reuse {
+		fmt.Printf("♻️ Resuming into existing table %s (%d rows already committed)\n", tableName, cp.rows)
+	} else if err := setupTable(db, tableName, columns); err != nil {
+		return 0, err
 	}
 
-	totalLines, err := ProcessPipelineParallel(db, csvPath, tableName, cleanHeaders)
+	totalLines, err := ProcessPipelineParallel(db, csvPath, tableName, schema, rejects, cp)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	elapsed := time.Since(start)
@@ -51,7 +58,7 @@ func ProcessCSVBlazingFast(db *sql.DB, csvPath, tableName string) error {
 	fmt.Printf("🔥 ULTRA FAST: %d lines in %.2f sec (%.0f lines/sec)\n",
 		totalLines, elapsed.Seconds(), linesPerSec)
 
-	return nil
+	return totalLines, nil
 }
 
 func setupTable(db *sql.DB, tableName string, columns []string) error {
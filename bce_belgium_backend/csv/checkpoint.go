@@ -0,0 +1,288 @@
+package csv
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	RUN_RUNNING   = "running"
+	RUN_COMPLETED = "completed"
+	RUN_FAILED    = "failed"
+
+	FILE_LOADING = "loading"
+	FILE_LOADED  = "loaded"
+
+	BATCH_SIZE = 200000
+)
+
+// ImportRun is one attempt at a full import. Swapped is set in the swap
+// transaction, so a resumed run that already went live only redoes what
+// follows the swap.
+type ImportRun struct {
+	ID      int64
+	Resumed bool
+	Swapped bool
+}
+
+// fileCheckpoint tracks the batches of one file already committed for a run.
+// Batches are numbered from the start of the file, so a resumed import can
+// skip them without duplicating rows.
+type fileCheckpoint struct {
+	runID      int64
+	file       string
+	status     string
+	rows       int64
+	rejects    int
+	committed  map[int]bool
+	reuseTable bool
+}
+
+type recordBatch struct {
+	Index        int
+	Records      []csvRecord
+	EndOffset    int64
+	ParseRejects int
+}
+
+func setupImportLog(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS import_runs (
+			id BIGSERIAL PRIMARY KEY,
+			source TEXT NOT NULL,
+			status TEXT NOT NULL,
+			started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			finished_at TIMESTAMPTZ
+		)`,
+		`CREATE TABLE IF NOT EXISTS import_files (
+			run_id BIGINT NOT NULL REFERENCES import_runs(id) ON DELETE CASCADE,
+			file_name TEXT NOT NULL,
+			table_name TEXT NOT NULL,
+			status TEXT NOT NULL,
+			rejects INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (run_id, file_name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS import_batches (
+			run_id BIGINT NOT NULL,
+			file_name TEXT NOT NULL,
+			batch_index INTEGER NOT NULL,
+			first_line BIGINT NOT NULL,
+			last_line BIGINT NOT NULL,
+			end_offset BIGINT NOT NULL,
+			rows INTEGER NOT NULL,
+			committed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (run_id, file_name, batch_index),
+			FOREIGN KEY (run_id, file_name) REFERENCES import_files(run_id, file_name) ON DELETE CASCADE
+		)`,
+		`ALTER TABLE import_runs
+			ADD COLUMN IF NOT EXISTS rows BIGINT NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS rejects INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE import_files
+			ADD COLUMN IF NOT EXISTS checksum TEXT,
+			ADD COLUMN IF NOT EXISTS extract_date DATE,
+			ADD COLUMN IF NOT EXISTS rows BIGINT NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			ADD COLUMN IF NOT EXISTS finished_at TIMESTAMPTZ`,
+		`ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS rejects INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE import_runs ADD COLUMN IF NOT EXISTS swapped_at TIMESTAMPTZ`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("error creating import log: %v", err)
+		}
+	}
+	return nil
+}
+
+// StartImportRun opens a new run, or with resume the latest unfinished run
+// for the same source.
+func StartImportRun(db *sql.DB, source string, resume bool) (*ImportRun, error) {
+	if err := setupImportLog(db); err != nil {
+		return nil, err
+	}
+
+	if resume {
+		var id int64
+		var swapped bool
+		err := db.QueryRow(`SELECT id, swapped_at IS NOT NULL FROM import_runs
+			WHERE source = $1 AND status <> $2 ORDER BY id DESC LIMIT 1`, source, RUN_COMPLETED).Scan(&id, &swapped)
+		switch {
+		case err == nil:
+			if _, err := db.Exec("UPDATE import_runs SET status = $2, finished_at = NULL WHERE id = $1", id, RUN_RUNNING); err != nil {
+				return nil, fmt.Errorf("reopen import run: %w", err)
+			}
+			fmt.Printf("♻️ Resuming import #%d\n", id)
+			return &ImportRun{ID: id, Resumed: true, Swapped: swapped}, nil
+		case !errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("find import run: %w", err)
+		}
+		fmt.Println("ℹ️ No interrupted import to resume, starting a new one")
+	}
+
+	run := &ImportRun{}
+	err := db.QueryRow("INSERT INTO import_runs (source, status) VALUES ($1, $2) RETURNING id",
+		source, RUN_RUNNING).Scan(&run.ID)
+	if err != nil {
+		return nil, fmt.Errorf("create import run: %w", err)
+	}
+	return run, nil
+}
+
+// markSwapped records, within the swap transaction, that the run is live.
+func (r *ImportRun) markSwapped(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE import_runs SET swapped_at = now() WHERE id = $1", r.ID); err != nil {
+		return fmt.Errorf("mark import run swapped: %w", err)
+	}
+	r.Swapped = true
+	return nil
+}
+
+func (r *ImportRun) Finish(db *sql.DB, status string) {
+	_, err := db.Exec(`UPDATE import_runs SET status = $2, finished_at = now(),
+			rows = (SELECT COALESCE(SUM(rows), 0) FROM import_files WHERE run_id = $1),
+			rejects = (SELECT COALESCE(SUM(rejects), 0) FROM import_files WHERE run_id = $1)
+		WHERE id = $1`, r.ID, status)
+	if err != nil {
+		fmt.Printf("❌ Import log error: %v\n", err)
+	}
+}
+
+// checkpoint returns the progress of a file in the run, registering it with
+// its checksum and extract date on first sight. A file whose checksum changed
+// since the interrupted attempt starts over.
+func (r *ImportRun) checkpoint(db *sql.DB, file, table, checksum string, extractDate *time.Time) (*fileCheckpoint, error) {
+	cp := &fileCheckpoint{runID: r.ID, file: file, committed: make(map[int]bool)}
+
+	var previousChecksum sql.NullString
+	err := db.QueryRow("SELECT status, rejects, checksum FROM import_files WHERE run_id = $1 AND file_name = $2",
+		r.ID, file).Scan(&cp.status, &cp.rejects, &previousChecksum)
+	if err == nil && previousChecksum.Valid && previousChecksum.String != checksum {
+		fmt.Printf("⚠️ %s changed since the interrupted attempt, importing it again\n", file)
+		if _, err := db.Exec("DELETE FROM import_files WHERE run_id = $1 AND file_name = $2", r.ID, file); err != nil {
+			return nil, fmt.Errorf("reset import file: %w", err)
+		}
+		err = sql.ErrNoRows
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = db.Exec(`INSERT INTO import_files (run_id, file_name, table_name, status, checksum, extract_date)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			r.ID, file, table, FILE_LOADING, checksum, extractDate)
+		if err != nil {
+			return nil, fmt.Errorf("record import file: %w", err)
+		}
+		cp.status = FILE_LOADING
+		cp.rejects = 0
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read import file: %w", err)
+	}
+
+	rows, err := db.Query("SELECT batch_index, rows, rejects FROM import_batches WHERE run_id = $1 AND file_name = $2", r.ID, file)
+	if err != nil {
+		return nil, fmt.Errorf("read import batches: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	// A file still loading counts the rejects of its committed batches; a
+	// loaded one keeps the total recorded by markLoaded.
+	batchRejects := 0
+	for rows.Next() {
+		var index, count, rejected int
+		if err := rows.Scan(&index, &count, &rejected); err != nil {
+			return nil, err
+		}
+		cp.committed[index] = true
+		cp.rows += int64(count)
+		batchRejects += rejected
+	}
+	if cp.status != FILE_LOADED {
+		cp.rejects = batchRejects
+	}
+
+	cp.reuseTable = true
+	return cp, rows.Err()
+}
+
+// canReuse reports whether the staging table of a resumed file can be kept.
+// Without it the committed batches are forgotten and the file starts over.
+func (cp *fileCheckpoint) canReuse(db *sql.DB, tableName string) (bool, error) {
+	if cp == nil || !cp.reuseTable {
+		return false, nil
+	}
+
+	exists, err := tableExists(context.Background(), db, tableName)
+	if err != nil || exists {
+		return exists, err
+	}
+
+	if _, err := db.Exec("DELETE FROM import_batches WHERE run_id = $1 AND file_name = $2", cp.runID, cp.file); err != nil {
+		return false, fmt.Errorf("reset checkpoint: %w", err)
+	}
+	cp.committed = make(map[int]bool)
+	cp.rows = 0
+	cp.rejects = 0
+	return false, nil
+}
+
+func (cp *fileCheckpoint) markLoaded(db *sql.DB, rows int64, rejects int) error {
+	_, err := db.Exec(`UPDATE import_files SET status = $3, rows = $4, rejects = $5, updated_at = now(), finished_at = now()
+		WHERE run_id = $1 AND file_name = $2`, cp.runID, cp.file, FILE_LOADED, rows, rejects)
+	return err
+}
+
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (cp *fileCheckpoint) isCommitted(index int) bool {
+	return cp != nil && cp.committed[index]
+}
+
+// commitBatch copies the batch and records it in import_batches, with its
+// rejected lines, within the same transaction.
+func (cp *fileCheckpoint) commitBatch(ctx context.Context, conn *pgx.Conn, tableName string, headers []string, batch recordBatch, rows [][]any) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin batch: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := copyRows(ctx, tx, tableName, headers, rows); err != nil {
+		return err
+	}
+
+	first := batch.Records[0].Line
+	last := batch.Records[len(batch.Records)-1].Line
+	rejected := batch.ParseRejects + len(batch.Records) - len(rows)
+	_, err = tx.Exec(ctx, `INSERT INTO import_batches (run_id, file_name, batch_index, first_line, last_line, end_offset, rows, rejects)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		cp.runID, cp.file, batch.Index, first, last, batch.EndOffset, len(rows), rejected)
+	if err != nil {
+		return fmt.Errorf("record checkpoint: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
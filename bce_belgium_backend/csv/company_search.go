@@ -0,0 +1,164 @@
+package csv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const COMPANY_SEARCH_TABLE = "company_search"
+
+var companySearchSources = []string{"enterprise", "denomination", "address", "contact", "activity", "establishment"}
+
+// companySearchQuery flattens every enterprise into one row: the main
+// denomination (language 2 first, as the search results always picked it),
+// the registered address (REGO), the main NACE code of the latest
+// nomenclature and the first contact of each type, every denomination in
+// names (one per line, so a substring search cannot match across two) for
+// the denomination search, plus the full detail rows as JSONB in
+// the shape the API returns them.
+const companySearchQuery = `
+	CREATE TABLE %s AS
+	WITH den AS (
+		SELECT entitynumber,
+			(array_agg(denomination ORDER BY (language = '2') DESC, typeofdenomination, denomination))[1] AS denomination,
+			jsonb_agg(jsonb_build_object('entitynumber', entitynumber, 'language', language, 'denomination', denomination)
+				ORDER BY language, denomination) AS denominations,
+			string_agg(denomination, E'\n') AS names,
+			string_agg(denomination, ' ') FILTER (WHERE language = '1') AS names_fr,
+			string_agg(denomination, ' ') FILTER (WHERE language = '2') AS names_nl,
+			string_agg(denomination, ' ') FILTER (WHERE language NOT IN ('1', '2')) AS names_other
+		FROM denomination
+		GROUP BY entitynumber
+	), adr AS (
+		SELECT entitynumber,
+			jsonb_agg(jsonb_build_object('entitynumber', entitynumber, 'typeofaddress', typeofaddress,
+				'zipcode', zipcode, 'municipalitynl', municipalitynl, 'municipalityfr', municipalityfr,
+				'streetnl', streetnl, 'streetfr', streetfr, 'housenumber', housenumber, 'box', box,
+				'extraaddressinfo', extraaddressinfo) ORDER BY typeofaddress) AS addresses
+		FROM address
+		GROUP BY entitynumber
+	), rego AS (
+		SELECT DISTINCT ON (entitynumber) entitynumber, zipcode, municipalityfr, municipalitynl, streetfr, housenumber
+		FROM address
+		WHERE typeofaddress = 'REGO'
+		ORDER BY entitynumber, datestrikingoff DESC NULLS FIRST
+	), con AS (
+		SELECT entitynumber,
+			(array_agg(value) FILTER (WHERE contacttype = 'EMAIL'))[1] AS email,
+			(array_agg(value) FILTER (WHERE contacttype = 'WEB'))[1] AS web,
+			(array_agg(value) FILTER (WHERE contacttype = 'TEL'))[1] AS tel,
+			(array_agg(value) FILTER (WHERE contacttype = 'FAX'))[1] AS fax,
+			jsonb_agg(jsonb_build_object('entitynumber', entitynumber, 'contacttype', contacttype, 'value', value)
+				ORDER BY contacttype, value) AS contacts
+		FROM contact
+		GROUP BY entitynumber
+	), act AS (
+		SELECT entitynumber,
+			(array_agg(nacecode ORDER BY naceversion DESC, activitygroup) FILTER (WHERE classification = 'MAIN'))[1] AS nacecode,
+			jsonb_agg(jsonb_build_object('entitynumber', entitynumber, 'activitygroup', activitygroup,
+				'naceversion', naceversion, 'nacecode', nacecode, 'classification', classification)
+				ORDER BY naceversion DESC, classification, nacecode) AS activities
+		FROM activity
+		GROUP BY entitynumber
+	), est AS (
+		SELECT enterprisenumber,
+			jsonb_agg(jsonb_build_object('establishmentnumber', establishmentnumber, 'enterprisenumber', enterprisenumber,
+				'startdate', TO_CHAR(startdate, 'DD-MM-YYYY')) ORDER BY establishmentnumber) AS establishments
+		FROM establishment
+		GROUP BY enterprisenumber
+	)
+	SELECT e.enterprisenumber, e.status, e.juridicalform, e.startdate,
+		den.denomination, rego.zipcode, rego.municipalityfr, rego.municipalitynl, rego.streetfr, rego.housenumber,
+		act.nacecode, con.email, con.web, con.tel, con.fax,
+		den.names, den.names_fr, den.names_nl, den.names_other,
+		COALESCE(den.denominations, '[]') AS denominations,
+		COALESCE(adr.addresses, '[]') AS addresses,
+		COALESCE(con.contacts, '[]') AS contacts,
+		COALESCE(act.activities, '[]') AS activities,
+		COALESCE(est.establishments, '[]') AS establishments
+	FROM enterprise e
+	LEFT JOIN den ON den.entitynumber = e.enterprisenumber
+	LEFT JOIN adr ON adr.entitynumber = e.enterprisenumber
+	LEFT JOIN rego ON rego.entitynumber = e.enterprisenumber
+	LEFT JOIN con ON con.entitynumber = e.enterprisenumber
+	LEFT JOIN act ON act.entitynumber = e.enterprisenumber
+	LEFT JOIN est ON est.enterprisenumber = e.enterprisenumber`
+
+// companySearchVector is the full-text column of company_search: every
+// denomination (legal names, abbreviations, commercial names) with the
+// configuration of its language, and the registered municipality in both
+// languages with a lower weight.
+const companySearchVector = `ALTER TABLE %s ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+	setweight(to_tsvector('french', COALESCE(names_fr, '')), 'A') ||
+	setweight(to_tsvector('dutch', COALESCE(names_nl, '')), 'A') ||
+	setweight(to_tsvector('simple', COALESCE(names_other, '')), 'A') ||
+	setweight(to_tsvector('french', COALESCE(municipalityfr, '')), 'C') ||
+	setweight(to_tsvector('dutch', COALESCE(municipalitynl, '')), 'C')
+) STORED`
+
+// BuildCompanySearch materializes company_search from the live tables into a
+// staging copy, indexes it and replaces the live one in a single transaction.
+// It runs after every import, update and rollback so it always matches the
+// tables it is built from.
+func BuildCompanySearch(db *sql.DB) error {
+	ctx := context.Background()
+	for _, table := range companySearchSources {
+		exists, err := tableExists(ctx, db, table)
+		if err != nil {
+			return fmt.Errorf("check %s: %w", table, err)
+		}
+		if !exists {
+			fmt.Printf("⏭️ %s not built, table %s does not exist\n", COMPANY_SEARCH_TABLE, table)
+			return nil
+		}
+	}
+
+	start := time.Now()
+	staging := COMPANY_SEARCH_TABLE + STAGING_SUFFIX
+	fmt.Printf("\n🧱 Building %s...\n", COMPANY_SEARCH_TABLE)
+
+	if _, err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", staging)); err != nil {
+		return fmt.Errorf("drop %s: %w", staging, err)
+	}
+	if _, err := db.Exec(fmt.Sprintf(companySearchQuery, staging)); err != nil {
+		return fmt.Errorf("build %s: %w", staging, err)
+	}
+	if _, err := db.Exec(fmt.Sprintf(companySearchVector, staging)); err != nil {
+		return fmt.Errorf("search vector on %s: %w", staging, err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s_pkey%s PRIMARY KEY (enterprisenumber)",
+		staging, COMPANY_SEARCH_TABLE, STAGING_SUFFIX)); err != nil {
+		return fmt.Errorf("primary key on %s: %w", staging, err)
+	}
+	if err := createStagingIndexes(db, []string{COMPANY_SEARCH_TABLE}); err != nil {
+		return err
+	}
+	if _, err := db.Exec(fmt.Sprintf("ANALYZE %s", staging)); err != nil {
+		return fmt.Errorf("analyze %s: %w", staging, err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin %s swap: %w", COMPANY_SEARCH_TABLE, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", COMPANY_SEARCH_TABLE)); err != nil {
+		return fmt.Errorf("drop %s: %w", COMPANY_SEARCH_TABLE, err)
+	}
+	if err := renameTable(ctx, tx, staging, COMPANY_SEARCH_TABLE, replaceSuffix(STAGING_SUFFIX, "")); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit %s swap: %w", COMPANY_SEARCH_TABLE, err)
+	}
+
+	var count int64
+	if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", COMPANY_SEARCH_TABLE)).Scan(&count); err != nil {
+		return fmt.Errorf("count %s: %w", COMPANY_SEARCH_TABLE, err)
+	}
+	fmt.Printf("✅ %s: %d enterprises in %.1fs\n", COMPANY_SEARCH_TABLE, count, time.Since(start).Seconds())
+	return nil
+}
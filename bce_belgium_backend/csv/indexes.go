@@ -0,0 +1,105 @@
+package csv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+type indexDef struct {
+	name       string
+	table      string
+	definition string
+}
+
+// indexes is the catalog built on the BCE tables. Entity numbers back the
+// enrichment lookups (entitynumber IN (...)), trigram indexes back the
+// ILIKE '%...%' searches on names and municipalities.
+var indexes = []indexDef{
+	{"idx_enterprise_number", "enterprise", "(enterprisenumber)"},
+	{"idx_enterprise_startdate", "enterprise", "(startdate, enterprisenumber)"},
+	{"idx_establishment_number", "establishment", "(establishmentnumber)"},
+	{"idx_establishment_enterprise", "establishment", "(enterprisenumber)"},
+	{"idx_branch_enterprise", "branch", "(enterprisenumber)"},
+	{"idx_denomination_entity", "denomination", "(entitynumber)"},
+	{"idx_denomination_trgm", "denomination", "USING gin(denomination gin_trgm_ops)"},
+	{"idx_address_entity", "address", "(entitynumber)"},
+	{"idx_address_zipcode", "address", "(zipcode, entitynumber) WHERE typeofaddress = 'REGO'"},
+	{"idx_address_municipalityfr_trgm", "address", "USING gin(municipalityfr gin_trgm_ops)"},
+	{"idx_address_municipalitynl_trgm", "address", "USING gin(municipalitynl gin_trgm_ops)"},
+	{"idx_contact_entity", "contact", "(entitynumber)"},
+	{"idx_activity_entity", "activity", "(entitynumber)"},
+	{"idx_activity_nacecode", "activity", "(nacecode, entitynumber) WHERE classification = 'MAIN'"},
+	{"idx_company_search_nacecode", "company_search", "(nacecode, enterprisenumber)"},
+	{"idx_company_search_zipcode", "company_search", "(zipcode, enterprisenumber)"},
+	{"idx_company_search_startdate", "company_search", "(startdate, enterprisenumber)"},
+	{"idx_company_search_names_trgm", "company_search", "USING gin(names gin_trgm_ops)"},
+	{"idx_company_search_fts", "company_search", "USING gin(search_vector)"},
+	{"idx_company_search_suggest", "company_search", `((upper(denomination)) COLLATE "C", enterprisenumber) WHERE status = 'AC'`},
+}
+
+func (idx indexDef) query(suffix string) string {
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s%s ON %s%s %s", idx.name, suffix, idx.table, suffix, idx.definition)
+}
+
+// CreateIndexes builds the catalog on the live tables. Tables that were never
+// imported are skipped.
+func CreateIndexes(db *sql.DB) error {
+	var selected []indexDef
+	for _, idx := range indexes {
+		exists, err := tableExists(context.Background(), db, idx.table)
+		if err != nil {
+			return fmt.Errorf("check %s: %w", idx.table, err)
+		}
+		if !exists {
+			fmt.Printf("⏭️ %s skipped, table %s does not exist\n", idx.name, idx.table)
+			continue
+		}
+		selected = append(selected, idx)
+	}
+	return createIndexes(db, selected, "")
+}
+
+// createStagingIndexes builds the catalog indexes of the given tables on
+// their staging copy, next to the ones copied from the live tables.
+func createStagingIndexes(db *sql.DB, tables []string) error {
+	var selected []indexDef
+	for _, idx := range indexes {
+		if slices.Contains(tables, idx.table) {
+			selected = append(selected, idx)
+		}
+	}
+	return createIndexes(db, selected, STAGING_SUFFIX)
+}
+
+func createIndexes(db *sql.DB, selected []indexDef, suffix string) error {
+	if _, err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm"); err != nil {
+		return fmt.Errorf("pg_trgm: %w", err)
+	}
+
+	fmt.Printf("🔧 Creating %d indexes...\n", len(selected))
+	totalStart := time.Now()
+	var failed []string
+
+	for i, idx := range selected {
+		start := time.Now()
+		fmt.Printf("   [%d/%d] %s%s... ", i+1, len(selected), idx.name, suffix)
+
+		if _, err := db.Exec(idx.query(suffix)); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			failed = append(failed, idx.name)
+			continue
+		}
+
+		fmt.Printf("✅ %.1fs\n", time.Since(start).Seconds())
+	}
+
+	fmt.Printf("🏁 Indexes done in %.1fs (%d/%d succeeded)\n",
+		time.Since(totalStart).Seconds(), len(selected)-len(failed), len(selected))
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d indexes failed: %v", len(failed), len(selected), failed)
+	}
+	return nil
+}
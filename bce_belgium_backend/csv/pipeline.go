@@ -2,31 +2,48 @@ package csv
 
 import (
 	"bufio"
+	"csv-importer/config"
+	"csv-importer/database"
 	"database/sql"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"runtime"
 	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func ProcessPipelineParallel(db *sql.DB, csvPath, tableName string, headers []string) (int, error) {
+func ProcessPipelineParallel(db *sql.DB, csvPath, tableName string, schema tableSchema, rejects *RejectWriter, cp *fileCheckpoint) (int, error) {
 	numWorkers := MinInt(runtime.NumCPU(), 8)
 
 	fmt.Printf("🚀 Using %d workers (pgx pipeline) (CPU cores: %d)\n", numWorkers, runtime.NumCPU())
 
-	recordChan := make(chan []string, 100000)
+	pool, err := database.NewPgxPool(config.Load(), numWorkers)
+	if err != nil {
+		return 0, err
+	}
+	defer pool.Close()
+
+	batchChan := make(chan recordBatch, numWorkers)
 	resultChan := make(chan int, numWorkers)
 
 	var wg sync.WaitGroup
 
+	// The reader's error is read once resultChan is closed, which happens
+	// after wg.Wait, so it needs no lock.
+	var readErr error
 	wg.Add(1)
-	go streamCSVReader(csvPath, recordChan, &wg)
+	go func() {
+		defer wg.Done()
+		readErr = streamCSVReader(csvPath, batchChan, rejects, cp)
+	}()
 
 	for i := range numWorkers {
 		wg.Add(1)
-		go streamWorker(i, tableName, headers, recordChan, resultChan, &wg)
+		go streamWorker(i, pool, tableName, schema, batchChan, resultChan, rejects, cp, &wg)
 	}
 
 	go func() {
@@ -38,47 +55,87 @@ func ProcessPipelineParallel(db *sql.DB, csvPath, tableName string, headers []st
 	for count := range resultChan {
 		totalLines += count
 	}
+	if readErr != nil {
+		return totalLines, fmt.Errorf("read %s: %w", tableName, readErr)
+	}
 
 	return totalLines, nil
 }
 
-func streamCSVReader(csvPath string, recordChan chan<- []string, wg *sync.WaitGroup) {
-	defer wg.Done()
-	defer close(recordChan)
+// streamCSVReader groups consecutive records into numbered batches of
+// BATCH_SIZE; batches already committed by a previous attempt are skipped.
+func streamCSVReader(csvPath string, batchChan chan<- recordBatch, rejects *RejectWriter, cp *fileCheckpoint) error {
+	defer close(batchChan)
 
 	file, err := os.Open(csvPath)
 	if err != nil {
-		fmt.Printf("❌ Reader error: %v\n", err)
-		return
+		return err
 	}
 	defer func() { _ = file.Close() }()
 
-	bufferedReader := bufio.NewReaderSize(file, 4*1024*1024)
+	capture := &captureReader{r: file}
+	bufferedReader := bufio.NewReaderSize(capture, 4*1024*1024)
 	reader := csv.NewReader(bufferedReader)
 	reader.Comma = ','
 	reader.ReuseRecord = true
 
-	_, _ = reader.Read()
+	if _, err := reader.Read(); err != nil && !isParseOrEOF(err) {
+		return err
+	}
 
 	lineCount := 0
+	skipped := 0
+	batch := recordBatch{Records: make([]csvRecord, 0, BATCH_SIZE)}
+
+	// Malformed lines are held with their batch and only written when the
+	// batch is not already committed, so a resume does not report them twice.
+	var pending []parseReject
+	emit := func(endOffset int64) {
+		batch.EndOffset = endOffset
+		batch.ParseRejects = len(pending)
+		if cp.isCommitted(batch.Index) {
+			skipped += len(batch.Records)
+			batch.Records = batch.Records[:0]
+		} else {
+			for _, r := range pending {
+				rejects.Add(REJECT_PARSE, r.line, r.raw, r.err)
+			}
+			batchChan <- batch
+			batch.Records = make([]csvRecord, 0, BATCH_SIZE)
+		}
+		pending = pending[:0]
+		batch.Index++
+	}
+
 	for {
+		start := reader.InputOffset()
 		record, err := reader.Read()
+		end := reader.InputOffset()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
+
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return err
+			}
+			pending = append(pending, parseReject{line: parseErr.StartLine, raw: capture.raw(start, end), err: err})
+			capture.release(end)
 			continue
 		}
 
+		line, _ := reader.FieldPos(0)
+		capture.release(end)
+
 		recordCopy := make([]string, len(record))
 		copy(recordCopy, record)
 
-		select {
-		case recordChan <- recordCopy:
-			lineCount++
-		default:
-			recordChan <- recordCopy
-			lineCount++
+		batch.Records = append(batch.Records, csvRecord{Line: line, Fields: recordCopy})
+		lineCount++
+
+		if len(batch.Records) >= BATCH_SIZE {
+			emit(end)
 		}
 
 		if lineCount%2000000 == 0 {
@@ -86,35 +143,49 @@ func streamCSVReader(csvPath string, recordChan chan<- []string, wg *sync.WaitGr
 		}
 	}
 
+	if len(batch.Records) > 0 {
+		emit(reader.InputOffset())
+	}
+	for _, r := range pending {
+		rejects.Add(REJECT_PARSE, r.line, r.raw, r.err)
+	}
+
+	if skipped > 0 {
+		fmt.Printf("♻️ Skipped %d rows already committed\n", skipped)
+	}
 	fmt.Printf("📖 Reader finished: %.1fM lines\n", float64(lineCount)/1000000)
+	return nil
+}
+
+type parseReject struct {
+	line int
+	raw  string
+	err  error
+}
+
+func isParseOrEOF(err error) bool {
+	var parseErr *csv.ParseError
+	return err == io.EOF || errors.As(err, &parseErr)
 }
 
-func streamWorker(workerID int, tableName string, headers []string, recordChan <-chan []string, resultChan chan<- int, wg *sync.WaitGroup) {
+func streamWorker(workerID int, pool *pgxpool.Pool, tableName string, schema tableSchema, batchChan <-chan recordBatch, resultChan chan<- int, rejects *RejectWriter, cp *fileCheckpoint, wg *sync.WaitGroup) {
 	defer wg.Done()
 
+	worker := &copyWorker{pool: pool}
+	defer worker.release()
+
 	lineCount := 0
-	batchSize := 200000
-	batch := make([][]string, 0, batchSize)
 
 	fmt.Printf("⚡ pgx Ultra Worker %d started\n", workerID)
 
-	for record := range recordChan {
-		batch = append(batch, record)
-
-		if len(batch) >= batchSize {
-			if err := InsertBatch(tableName, headers, batch); err != nil {
-				fmt.Printf("❌ Worker %d batch error: %v\n", workerID, err)
-				continue
-			}
-			lineCount += len(batch)
-			batch = batch[:0]
-		}
-	}
-
-	if len(batch) > 0 {
-		if err := InsertBatch(tableName, headers, batch); err == nil {
-			lineCount += len(batch)
+	for batch := range batchChan {
+		rows := schema.convertBatch(batch, rejects)
+		if err := worker.insertRecordBatch(tableName, schema.Headers, batch, rows, cp); err != nil {
+			fmt.Printf("❌ Worker %d batch error (%d rows rejected): %v\n", workerID, len(rows), err)
+			rejects.AddBatch(batch.Records, err)
+			continue
 		}
+		lineCount += len(rows)
 	}
 
 	resultChan <- lineCount
@@ -0,0 +1,176 @@
+package csv
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"csv-importer/identifier"
+)
+
+const (
+	COL_TEXT = "TEXT"
+	COL_DATE = "DATE"
+
+	// KBO open data writes dates as dd-mm-yyyy.
+	BCE_DATE_LAYOUT = "02-01-2006"
+)
+
+// tableSchemas lists the typed columns of each table, any other column is
+// TEXT. Enterprise numbers and codes stay TEXT to keep their leading zeros.
+var tableSchemas = map[string]map[string]string{
+	"enterprise":    {"startdate": COL_DATE},
+	"establishment": {"startdate": COL_DATE},
+	"branch":        {"startdate": COL_DATE},
+	"address":       {"datestrikingoff": COL_DATE},
+}
+
+// identifierColumns lists the columns holding a KBO number. A number that
+// fails its mod 97 check is loaded as is and flagged in the reject file.
+var identifierColumns = map[string]map[string]func(string) error{
+	"enterprise":    {"enterprisenumber": validEnterprise},
+	"establishment": {"establishmentnumber": validEstablishment, "enterprisenumber": validEnterprise},
+	"branch":        {"enterprisenumber": validEnterprise},
+	"denomination":  {"entitynumber": identifier.ValidEntityNumber},
+	"address":       {"entitynumber": identifier.ValidEntityNumber},
+	"contact":       {"entitynumber": identifier.ValidEntityNumber},
+	"activity":      {"entitynumber": identifier.ValidEntityNumber},
+}
+
+func validEnterprise(value string) error {
+	_, err := identifier.NormalizeEnterprise(value)
+	return err
+}
+
+func validEstablishment(value string) error {
+	_, err := identifier.NormalizeEstablishment(value)
+	return err
+}
+
+// tableSchema is the COPY column list of a file with the type of each column.
+type tableSchema struct {
+	Headers []string
+	Types   []string
+	checks  []identifierCheck
+}
+
+type identifierCheck struct {
+	index int
+	valid func(string) error
+}
+
+// identifierChecks returns the identifier checks of table for its headers.
+func identifierChecks(table string, headers []string) []identifierCheck {
+	var checks []identifierCheck
+	for i, header := range headers {
+		if valid, ok := identifierColumns[table][header]; ok {
+			checks = append(checks, identifierCheck{index: i, valid: valid})
+		}
+	}
+	return checks
+}
+
+func columnType(table, column string) string {
+	if typ, ok := tableSchemas[table][column]; ok {
+		return typ
+	}
+	return COL_TEXT
+}
+
+// liveSchema takes the column types from the live table, so update files are
+// staged the way that table was created. Columns unknown to it stay TEXT.
+func liveSchema(db *sql.DB, table string, headers []string) (tableSchema, error) {
+	rows, err := db.Query(`SELECT column_name, data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1`, table)
+	if err != nil {
+		return tableSchema{}, fmt.Errorf("read columns of %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	dataTypes := make(map[string]string)
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return tableSchema{}, err
+		}
+		dataTypes[name] = dataType
+	}
+	if err := rows.Err(); err != nil {
+		return tableSchema{}, err
+	}
+
+	schema := tableSchema{Headers: headers, Types: make([]string, len(headers)), checks: identifierChecks(table, headers)}
+	for i, header := range headers {
+		schema.Types[i] = COL_TEXT
+		if dataTypes[header] == "date" {
+			schema.Types[i] = COL_DATE
+		}
+	}
+	return schema, nil
+}
+
+func (s tableSchema) columns() []string {
+	columns := make([]string, len(s.Headers))
+	for i, header := range s.Headers {
+		columns[i] = header + " " + s.Types[i]
+	}
+	return columns
+}
+
+// convertBatch turns the records of a batch into COPY rows. Records with a
+// value that does not match its column type are sent to the reject file.
+func (s tableSchema) convertBatch(batch recordBatch, rejects *RejectWriter) [][]any {
+	rows := make([][]any, 0, len(batch.Records))
+	for _, record := range batch.Records {
+		row, err := s.convertRecord(record.Fields)
+		if err != nil {
+			rejects.Add(REJECT_CONVERT, record.Line, encodeRecord(record.Fields), err)
+			continue
+		}
+		s.flagIdentifiers(record, rejects)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// flagIdentifiers writes the record to the reject file, without rejecting
+// it, when one of its identifiers is invalid. Empty identifiers are skipped.
+func (s tableSchema) flagIdentifiers(record csvRecord, rejects *RejectWriter) {
+	for _, check := range s.checks {
+		if check.index >= len(record.Fields) {
+			continue
+		}
+		field := strings.TrimSpace(record.Fields[check.index])
+		if field == "" {
+			continue
+		}
+		if err := check.valid(field); err != nil {
+			rejects.Flag(record.Line, encodeRecord(record.Fields), fmt.Errorf("column %s: %w", s.Headers[check.index], err))
+			return
+		}
+	}
+}
+
+func (s tableSchema) convertRecord(fields []string) ([]any, error) {
+	row := make([]any, len(fields))
+	for i, field := range fields {
+		if i >= len(s.Types) || s.Types[i] == COL_TEXT {
+			row[i] = field
+			continue
+		}
+
+		field = strings.TrimSpace(field)
+		if field == "" {
+			row[i] = nil
+			continue
+		}
+
+		date, err := time.Parse(BCE_DATE_LAYOUT, field)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: invalid date %q (expected dd-mm-yyyy)", s.Headers[i], field)
+		}
+		row[i] = date
+	}
+	return row, nil
+}
@@ -0,0 +1,315 @@
+package csv
+
+import (
+	"context"
+	"csv-importer/config"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type updateTable struct {
+	Name string
+	Key  string
+}
+
+var updateTables = []updateTable{
+	{Name: "enterprise", Key: "enterprisenumber"},
+	{Name: "denomination", Key: "entitynumber"},
+	{Name: "address", Key: "entitynumber"},
+	{Name: "contact", Key: "entitynumber"},
+	{Name: "activity", Key: "entitynumber"},
+	{Name: "establishment", Key: "establishmentnumber"},
+	{Name: "branch", Key: "id"},
+}
+
+type UpdateExtract struct {
+	Dir           string
+	ExtractNumber int
+	Meta          [][]string
+}
+
+type updateStats struct {
+	Table    string
+	Deleted  int64
+	Inserted int64
+}
+
+func readUpdateMeta(dir string) (*UpdateExtract, error) {
+	file, err := os.Open(filepath.Join(dir, "meta.csv"))
+	if err != nil {
+		return nil, fmt.Errorf("impossible to open meta.csv in %s: %v", dir, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ','
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading meta.csv: %v", err)
+	}
+
+	extract := &UpdateExtract{Dir: dir}
+	for _, record := range records[1:] {
+		if len(record) < 2 {
+			continue
+		}
+		extract.Meta = append(extract.Meta, record[:2])
+		if record[0] == "ExtractNumber" {
+			extract.ExtractNumber, err = strconv.Atoi(strings.TrimSpace(record[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid ExtractNumber %q: %v", record[1], err)
+			}
+		}
+	}
+
+	if extract.ExtractNumber == 0 {
+		return nil, fmt.Errorf("meta.csv in %s has no ExtractNumber", dir)
+	}
+
+	return extract, nil
+}
+
+func getUpdateExtracts(updateDir string) ([]*UpdateExtract, error) {
+	if _, err := os.Stat(filepath.Join(updateDir, "meta.csv")); err == nil {
+		extract, err := readUpdateMeta(updateDir)
+		if err != nil {
+			return nil, err
+		}
+		return []*UpdateExtract{extract}, nil
+	}
+
+	entries, err := os.ReadDir(updateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var extracts []*UpdateExtract
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		extract, err := readUpdateMeta(filepath.Join(updateDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		extracts = append(extracts, extract)
+	}
+
+	sort.Slice(extracts, func(i, j int) bool { return extracts[i].ExtractNumber < extracts[j].ExtractNumber })
+	return extracts, nil
+}
+
+func currentExtractNumber(db *sql.DB) (int, error) {
+	var value string
+	err := db.QueryRow("SELECT value FROM meta WHERE variable = 'ExtractNumber'").Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read current ExtractNumber from meta: %v", err)
+	}
+	return strconv.Atoi(strings.TrimSpace(value))
+}
+
+func ProcessAllUpdates(db *sql.DB, updateDir string) error {
+	extracts, err := getUpdateExtracts(updateDir)
+	if err != nil {
+		return fmt.Errorf("error scanning update directory: %v", err)
+	}
+
+	if len(extracts) == 0 {
+		return fmt.Errorf("no update extract found in %s", updateDir)
+	}
+
+	fmt.Printf("🔄 Applying %d update extracts\n", len(extracts))
+	totalStart := time.Now()
+
+	for _, extract := range extracts {
+		if err := ApplyUpdateExtract(db, extract); err != nil {
+			return fmt.Errorf("extract %d: %v", extract.ExtractNumber, err)
+		}
+	}
+
+	fmt.Printf("\n🏆 %d extracts applied in %.2f minutes\n", len(extracts), time.Since(totalStart).Minutes())
+	return BuildCompanySearch(db)
+}
+
+func ApplyUpdateExtract(db *sql.DB, extract *UpdateExtract) error {
+	current, err := currentExtractNumber(db)
+	if err != nil {
+		return err
+	}
+
+	if extract.ExtractNumber != current+1 {
+		return fmt.Errorf("out of sequence: database is at extract %d, update is %d (expected %d)",
+			current, extract.ExtractNumber, current+1)
+	}
+
+	fmt.Printf("\n📦 Extract %d (%s)\n", extract.ExtractNumber, extract.Dir)
+
+	var staged []string
+	defer func() {
+		for _, table := range staged {
+			_, _ = db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
+		}
+	}()
+
+	deletes := make(map[string]string)
+	inserts := make(map[string][]string)
+
+	for _, t := range updateTables {
+		deletePath := filepath.Join(extract.Dir, t.Name+"_delete.csv")
+		if _, err := os.Stat(deletePath); err == nil {
+			stagingTable := t.Name + "__delete"
+			headers, err := stageUpdateFile(db, deletePath, t.Name, stagingTable)
+			staged = append(staged, stagingTable)
+			if err != nil {
+				return err
+			}
+			if len(headers) != 1 {
+				return fmt.Errorf("%s must have a single key column, got %v", filepath.Base(deletePath), headers)
+			}
+			deletes[t.Name] = headers[0]
+		}
+
+		insertPath := filepath.Join(extract.Dir, t.Name+"_insert.csv")
+		if _, err := os.Stat(insertPath); err == nil {
+			stagingTable := t.Name + "__insert"
+			headers, err := stageUpdateFile(db, insertPath, t.Name, stagingTable)
+			staged = append(staged, stagingTable)
+			if err != nil {
+				return err
+			}
+			inserts[t.Name] = headers
+		}
+	}
+
+	stats, err := applyStagedUpdate(db, extract, deletes, inserts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n📊 SUMMARY:")
+	for _, s := range stats {
+		fmt.Printf("   • %s: -%d / +%d rows\n", s.Table, s.Deleted, s.Inserted)
+	}
+	fmt.Printf("✅ Database now at extract %d\n", extract.ExtractNumber)
+
+	return nil
+}
+
+func stageUpdateFile(db *sql.DB, csvPath, table, stagingTable string) ([]string, error) {
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("impossible to open %s: %v", csvPath, err)
+	}
+	reader := csv.NewReader(file)
+	reader.Comma = ','
+	headers, err := reader.Read()
+	_ = file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading header of %s: %v", csvPath, err)
+	}
+
+	cleanHeaders := make([]string, len(headers))
+	for i, header := range headers {
+		cleanHeaders[i] = CleanColumnName(header)
+	}
+
+	schema, err := liveSchema(db, table, cleanHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", stagingTable)); err != nil {
+		return nil, fmt.Errorf("error dropping staging table: %v", err)
+	}
+	createSQL := fmt.Sprintf("CREATE UNLOGGED TABLE %s (%s)", stagingTable, strings.Join(schema.columns(), ", "))
+	if _, err := db.Exec(createSQL); err != nil {
+		return nil, fmt.Errorf("error creating staging table: %v", err)
+	}
+
+	cfg := config.Load()
+	rejects := NewRejectWriter(cfg.RejectsDir, stagingTable)
+	totalLines, err := ProcessPipelineParallel(db, csvPath, stagingTable, schema, rejects, nil)
+	if closeErr := rejects.Close(); closeErr != nil {
+		fmt.Printf("❌ Reject file error: %v\n", closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRejectThreshold(rejects.Total(), cfg.ImportMaxRejects); err != nil {
+		return nil, fmt.Errorf("%v (%s), update not applied", err, rejects.Summary())
+	}
+
+	fmt.Printf("📥 %s → %s (%d rows, %s)\n", filepath.Base(csvPath), stagingTable, totalLines, rejects.Summary())
+	return cleanHeaders, nil
+}
+
+// applyStagedUpdate runs every delete and insert set of the extract plus the
+// meta update in one transaction, so a failure leaves the previous extract intact.
+func applyStagedUpdate(db *sql.DB, extract *UpdateExtract, deletes map[string]string, inserts map[string][]string) ([]updateStats, error) {
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin update: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var stats []updateStats
+	for _, t := range updateTables {
+		s := updateStats{Table: t.Name}
+
+		if deleteKey, ok := deletes[t.Name]; ok {
+			res, err := tx.ExecContext(ctx, fmt.Sprintf(
+				"DELETE FROM %[1]s t USING %[1]s__delete d WHERE t.%[2]s = d.%[3]s",
+				t.Name, t.Key, deleteKey))
+			if err != nil {
+				return nil, fmt.Errorf("delete from %s: %w", t.Name, err)
+			}
+			s.Deleted, _ = res.RowsAffected()
+		}
+
+		if headers, ok := inserts[t.Name]; ok {
+			columnList := strings.Join(headers, ", ")
+			res, err := tx.ExecContext(ctx, fmt.Sprintf(
+				"INSERT INTO %[1]s (%[2]s) SELECT %[2]s FROM %[1]s__insert",
+				t.Name, columnList))
+			if err != nil {
+				return nil, fmt.Errorf("insert into %s: %w", t.Name, err)
+			}
+			s.Inserted, _ = res.RowsAffected()
+		}
+
+		stats = append(stats, s)
+	}
+
+	var current int
+	err = tx.QueryRowContext(ctx, "SELECT value::int FROM meta WHERE variable = 'ExtractNumber' FOR UPDATE").Scan(&current)
+	if err != nil {
+		return nil, fmt.Errorf("lock meta: %w", err)
+	}
+	if current+1 != extract.ExtractNumber {
+		return nil, fmt.Errorf("out of sequence: database moved to extract %d during update", current)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM meta"); err != nil {
+		return nil, fmt.Errorf("clear meta: %w", err)
+	}
+	for _, record := range extract.Meta {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO meta (variable, value) VALUES ($1, $2)", record[0], record[1]); err != nil {
+			return nil, fmt.Errorf("write meta: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit update: %w", err)
+	}
+
+	return stats, nil
+}
@@ -13,17 +13,19 @@ func CleanColumnName(header string) string {
 	return cleanHeader
 }
 
-func PrepareHeaders(headers []string) ([]string, []string) {
-	cleanHeaders := make([]string, len(headers))
-	var columns []string
+// PrepareHeaders cleans the CSV headers and types them with the schema of
+// table, returning the COPY schema and the column definitions.
+func PrepareHeaders(table string, headers []string) (tableSchema, []string) {
+	schema := tableSchema{Headers: make([]string, len(headers)), Types: make([]string, len(headers))}
 
 	for i, header := range headers {
 		cleanHeader := CleanColumnName(header)
-		cleanHeaders[i] = cleanHeader
-		columns = append(columns, cleanHeader+" TEXT")
+		schema.Headers[i] = cleanHeader
+		schema.Types[i] = columnType(table, cleanHeader)
 	}
+	schema.checks = identifierChecks(table, schema.Headers)
 
-	return cleanHeaders, columns
+	return schema, schema.columns()
 }
 
 func OptimizeForBulkInsert(db *sql.DB) error {
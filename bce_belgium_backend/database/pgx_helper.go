@@ -4,8 +4,10 @@ import (
 	"context"
 	"csv-importer/config"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func ConnectPgxNative(cfg *config.Config) (*pgx.Conn, error) {
@@ -25,6 +27,40 @@ func ConnectPgxNative(cfg *config.Config) (*pgx.Conn, error) {
 	return conn, nil
 }
 
+// NewPgxPool opens a pool of size long-lived connections, one per import
+// worker. Broken connections are replaced on the next Acquire.
+func NewPgxPool(cfg *config.Config, size int) (*pgxpool.Pool, error) {
+	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBHost,
+		cfg.DBPort,
+		cfg.DBName,
+	)
+
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool config: %w", err)
+	}
+
+	poolCfg.MaxConns = int32(size)
+	poolCfg.MinConns = int32(size)
+	poolCfg.MaxConnIdleTime = 5 * time.Minute
+	poolCfg.HealthCheckPeriod = 30 * time.Second
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pgx pool: %w", err)
+	}
+
+	if err := pool.Ping(context.Background()); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping with pgx pool: %w", err)
+	}
+
+	return pool, nil
+}
+
 func CopyFromSlice(conn *pgx.Conn, tableName string, columnNames []string, rows [][]any) (int64, error) {
 	return conn.CopyFrom(
 		context.Background(),
@@ -0,0 +1,108 @@
+// Package identifier validates and normalizes KBO/BCE numbers. Enterprise
+// and establishment numbers are 10 digits whose last two are the modulo 97
+// check of the first eight.
+package identifier
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const NUMBER_LENGTH = 10
+
+const (
+	KIND_ENTERPRISE    = "enterprise"
+	KIND_ESTABLISHMENT = "establishment"
+)
+
+// digits strips the BE prefix and the separators users type (dots, spaces,
+// dashes, slashes) from a number.
+func digits(value string) string {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	value = strings.TrimPrefix(value, "BE")
+
+	var sb strings.Builder
+	for _, r := range value {
+		switch r {
+		case '.', ' ', '-', '/':
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func checkNumber(kind, value, number string) error {
+	if len(number) != NUMBER_LENGTH {
+		return fmt.Errorf("invalid %s %q: expected 10 digits", kind, value)
+	}
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("invalid %s %q: only digits, dots, spaces and a BE prefix are allowed", kind, value)
+		}
+	}
+
+	base, _ := strconv.Atoi(number[:8])
+	check, _ := strconv.Atoi(number[8:])
+	if 97-base%97 != check {
+		return fmt.Errorf("invalid %s %q: modulo 97 check digits do not match", kind, value)
+	}
+	return nil
+}
+
+// NormalizeEnterprise accepts 0123.456.789, 0123456789, BE 0123 456 789 and
+// the old 9-digit form, and returns the dotted form stored by KBO.
+func NormalizeEnterprise(value string) (string, error) {
+	number := digits(value)
+	if len(number) == NUMBER_LENGTH-1 {
+		number = "0" + number
+	}
+
+	if err := checkNumber("enterprise number", value, number); err != nil {
+		return "", err
+	}
+	if number[0] != '0' && number[0] != '1' {
+		return "", fmt.Errorf("invalid enterprise number %q: must start with 0 or 1", value)
+	}
+	return number[:4] + "." + number[4:7] + "." + number[7:], nil
+}
+
+// NormalizeEstablishment accepts 2.123.456.789 or 2123456789 and returns the
+// dotted form stored by KBO.
+func NormalizeEstablishment(value string) (string, error) {
+	number := digits(value)
+	if err := checkNumber("establishment number", value, number); err != nil {
+		return "", err
+	}
+	if number[0] < '2' || number[0] > '8' {
+		return "", fmt.Errorf("invalid establishment number %q: must start with 2 to 8", value)
+	}
+	return number[:1] + "." + number[1:4] + "." + number[4:7] + "." + number[7:], nil
+}
+
+// NormalizeEntity normalizes an enterprise or an establishment number,
+// telling them apart by their first digit.
+func NormalizeEntity(value string) (kind, number string, err error) {
+	if isEstablishment(digits(value)) {
+		number, err = NormalizeEstablishment(value)
+		return KIND_ESTABLISHMENT, number, err
+	}
+	number, err = NormalizeEnterprise(value)
+	return KIND_ENTERPRISE, number, err
+}
+
+func isEstablishment(number string) bool {
+	return len(number) == NUMBER_LENGTH && number[0] >= '2' && number[0] <= '8'
+}
+
+// ValidEntityNumber checks an entity number of a KBO file. Branch numbers
+// (starting with 9) carry no check digits and are only checked for length.
+func ValidEntityNumber(value string) error {
+	number := digits(value)
+	if len(number) == NUMBER_LENGTH && number[0] == '9' {
+		return nil
+	}
+	_, _, err := NormalizeEntity(value)
+	return err
+}
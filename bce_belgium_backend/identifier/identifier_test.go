@@ -0,0 +1,70 @@
+package identifier
+
+import "testing"
+
+func TestCheckNumber(t *testing.T) {
+	tests := []struct {
+		number string
+		valid  bool
+	}{
+		{"0403170701", true},
+		{"0417497106", true},
+		{"0202239951", true},
+		{"2143286363", true},
+		{"0403170702", false},
+		{"0417497160", false},
+		{"2143286364", false},
+		{"040317070", false},
+		{"04031707O1", false},
+	}
+
+	for _, tt := range tests {
+		if err := checkNumber("number", tt.number, tt.number); (err == nil) != tt.valid {
+			t.Errorf("checkNumber(%q) = %v, want valid %v", tt.number, err, tt.valid)
+		}
+	}
+}
+
+func TestNormalizeEnterprise(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		valid bool
+	}{
+		{"0403.170.701", "0403.170.701", true},
+		{"0403170701", "0403.170.701", true},
+		{"BE 0417 497 106", "0417.497.106", true},
+		{"be0417.497.106", "0417.497.106", true},
+		{"417497106", "0417.497.106", true},
+		{"0403.170.702", "", false},
+		{"2.143.286.363", "", false},
+		{"0403.170", "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeEnterprise(tt.value)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("NormalizeEnterprise(%q) = %q, %v, want %q, valid %v", tt.value, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestNormalizeEstablishment(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		valid bool
+	}{
+		{"2.143.286.363", "2.143.286.363", true},
+		{"2143286363", "2.143.286.363", true},
+		{"2.143.286.364", "", false},
+		{"0403.170.701", "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeEstablishment(tt.value)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("NormalizeEstablishment(%q) = %q, %v, want %q, valid %v", tt.value, got, err, tt.want, tt.valid)
+		}
+	}
+}
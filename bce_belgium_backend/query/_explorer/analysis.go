@@ -11,7 +11,7 @@ func CountRows(db *sql.DB, tableName, columnName, searchValue string) error {
 	query := fmt.Sprintf(`
 		SELECT count(*)
 		FROM %s
-		WHERE %s ILIKE $1
+		WHERE %s::text ILIKE $1
 	`, tableName, columnName)
 
 	var count int64
@@ -67,7 +67,7 @@ func SampleRows(db *sql.DB, tableName, columnName, searchValue string, limit int
 	sampleQuery := fmt.Sprintf(`
 		SELECT %s
 		FROM %s
-		WHERE %s ILIKE $1
+		WHERE %s::text ILIKE $1
 		LIMIT %d
 	`, strings.Join(columns, ", "), tableName, columnName, limit)
 
@@ -157,7 +157,7 @@ func ExportToCSV(db *sql.DB, tableName, columnName, searchValue, filename string
 	}
 
 	// Count first
-	countQuery := fmt.Sprintf(`SELECT count(*) FROM %s WHERE %s ILIKE $1`, tableName, columnName)
+	countQuery := fmt.Sprintf(`SELECT count(*) FROM %s WHERE %s::text ILIKE $1`, tableName, columnName)
 	var totalRows int64
 	db.QueryRow(countQuery, "%"+searchValue+"%").Scan(&totalRows)
 
@@ -167,7 +167,7 @@ func ExportToCSV(db *sql.DB, tableName, columnName, searchValue, filename string
 	exportQuery := fmt.Sprintf(`
 		SELECT %s
 		FROM %s
-		WHERE %s ILIKE $1
+		WHERE %s::text ILIKE $1
 	`, strings.Join(columns, ", "), tableName, columnName)
 
 	exportRows, err := db.Query(exportQuery, "%"+searchValue+"%")
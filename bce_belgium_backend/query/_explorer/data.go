@@ -87,7 +87,7 @@ func SearchTable(db *sql.DB, tableName, columnName, searchValue string, limit in
 	}
 
 	query := "SELECT " + columnName + " FROM " + tableName +
-		" WHERE " + columnName + " ILIKE $1 LIMIT $2"
+		" WHERE " + columnName + "::text ILIKE $1 LIMIT $2"
 
 	rows, err := db.Query(query, "%"+searchValue+"%", limit)
 	if err != nil {
@@ -1,6 +1,7 @@
 package models
 
 type CompanySearchCriteria struct {
+	Query              string `json:"query,omitempty"`
 	Siren              string `json:"siren,omitempty"`
 	Siret              string `json:"siret,omitempty"`
 	NafCode            string `json:"naf_code,omitempty"`
@@ -37,6 +38,18 @@ type CompanyResult struct {
 	Website             string           `json:"website,omitempty"`
 	UniteLegale         map[string]any   `json:"unite_legale,omitempty"`
 	Etablissements      []map[string]any `json:"etablissements,omitempty"`
+	Restricted          bool             `json:"restricted"`
+	RestrictedFields    []string         `json:"restricted_fields,omitempty"`
+	Score               float64          `json:"score,omitempty"`
+	Highlight           string           `json:"highlight,omitempty"`
+}
+
+type Suggestion struct {
+	Denomination   string `json:"denomination"`
+	Siren          string `json:"siren"`
+	Siret          string `json:"siret,omitempty"`
+	CodePostal     string `json:"code_postal,omitempty"`
+	LibelleCommune string `json:"libelle_commune,omitempty"`
 }
 
 type CompanySearchResult struct {
@@ -44,3 +57,24 @@ type CompanySearchResult struct {
 	Results  []CompanyResult       `json:"results"`
 	Meta     Meta                  `json:"meta"`
 }
+
+const (
+	LOOKUP_FOUND     = "found"
+	LOOKUP_NOT_FOUND = "not_found"
+	LOOKUP_INVALID   = "invalid"
+)
+
+type LookupRequest struct {
+	Identifiers []string `json:"identifiers"`
+}
+
+// LookupResult answers one identifier of a batch lookup. A SIREN returns its
+// siege, a SIRET the establishment itself.
+type LookupResult struct {
+	Input      string         `json:"input"`
+	Kind       string         `json:"kind,omitempty"`
+	Identifier string         `json:"identifier,omitempty"`
+	Status     string         `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	Company    *CompanyResult `json:"company,omitempty"`
+}
@@ -8,13 +8,31 @@ type APIResponse struct {
 }
 
 type Meta struct {
-	Count    int   `json:"count,omitempty"`
-	Total    int   `json:"total,omitempty"`
-	Limit    int   `json:"limit,omitempty"`
-	Offset   int   `json:"offset,omitempty"`
-	Page     int   `json:"page,omitempty"`
-	Pages    int   `json:"pages,omitempty"`
-	Duration int64 `json:"duration_ms,omitempty"`
+	Count      int    `json:"count,omitempty"`
+	Total      int    `json:"total,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	Offset     int    `json:"offset,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	Pages      int    `json:"pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Duration   int64  `json:"duration_ms,omitempty"`
+}
+
+// PageRequest selects a page by offset, or by keyset when After is set.
+type PageRequest struct {
+	Limit  int
+	Offset int
+	After  *Cursor
+}
+
+// Cursor is the (date_creation, siren) position of the last row of a page.
+type Cursor struct {
+	DateCreation string
+	Siren        string
+}
+
+func (c Cursor) String() string {
+	return c.DateCreation + "," + c.Siren
 }
 
 func Success(data any) APIResponse {
@@ -1,13 +1,17 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"github.com/gin-gonic/gin"
 	"github.com/lmittmann/tint"
 	"log/slog"
 	"net/http"
 	"os"
+	"sirene-importer/api/services/admin"
 	"sirene-importer/api/services/company"
+	"sirene-importer/api/services/imports"
+	"sirene-importer/api/services/jobs"
 	"sirene-importer/api/services/naf"
 	"sirene-importer/config"
 	"sirene-importer/database"
@@ -20,6 +24,9 @@ type Server struct {
 	logger         *slog.Logger
 	companyHandler *company.Handler
 	nafHandler     *naf.Handler
+	importHandler  *imports.Handler
+	adminHandler   *admin.Handler
+	jobHandler     *jobs.Handler
 }
 
 func StartAPIServer() {
@@ -50,12 +57,19 @@ func NewServer(db *sql.DB) *Server {
 	companyHandler := company.NewHandler(companyService)
 	nafService := naf.NewNafService(db)
 	nafHandler := naf.NewHandler(nafService)
+	importHandler := imports.NewHandler(imports.NewImportService(db))
+	adminHandler := admin.NewHandler(admin.NewAdminService(db))
+	jobService := jobs.NewJobService(db, companyService)
+	go jobService.Run(context.Background())
 	s := &Server{
 		db:             db,
 		router:         gin.Default(),
 		logger:         logger,
 		companyHandler: companyHandler,
 		nafHandler:     nafHandler,
+		importHandler:  importHandler,
+		adminHandler:   adminHandler,
+		jobHandler:     jobs.NewHandler(jobService),
 	}
 	s.setupRoutes()
 	return s
@@ -73,14 +87,25 @@ func (s *Server) setupRoutes() {
 		c.JSON(http.StatusOK, gin.H{"status": "ok", "service": "sirene-france"})
 	})
 	companies := api.Group("/companies")
+	companies.GET("/search", s.companyHandler.SearchFullText)
+	companies.GET("/suggest", s.companyHandler.Suggest)
 	companies.GET("/search/naf", s.companyHandler.SearchByNafCode)
 	companies.GET("/search/denomination", s.companyHandler.SearchByDenomination)
+	companies.GET("/search/name", s.companyHandler.SearchByName)
 	companies.GET("/search/codepostal", s.companyHandler.SearchByCodePostal)
 	companies.GET("/search/commune", s.companyHandler.SearchByCommune)
 	companies.GET("/search/etatadministratif", s.companyHandler.SearchByEtatAdministratif)
 	companies.GET("/search/datecreation", s.companyHandler.SearchByDateCreation)
 	companies.GET("/search/multi", s.companyHandler.SearchMultiCriteria)
 	companies.GET("/lookup/:identifier", s.companyHandler.SearchByIdentifier)
+	companies.POST("/lookup", s.companyHandler.LookupIdentifiers)
+	companies.GET("/:siren", s.companyHandler.GetCompanyProfile)
+	api.GET("/imports", s.importHandler.ListImports)
+	api.GET("/admin/indexes", s.adminHandler.Indexes)
+	jobGroup := api.Group("/jobs")
+	jobGroup.POST("/enrich", s.jobHandler.SubmitEnrichJob)
+	jobGroup.GET("/:id", s.jobHandler.GetJob)
+	jobGroup.GET("/:id/result", s.jobHandler.GetJobResult)
 	nafGroup := api.Group("/naf")
 	nafGroup.GET("/search", s.nafHandler.SearchByLabel)
 	nafGroup.GET("/sections", s.nafHandler.ListSections)
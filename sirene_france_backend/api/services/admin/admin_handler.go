@@ -0,0 +1,27 @@
+package admin
+
+import (
+	"net/http"
+	"sirene-importer/api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *adminService
+}
+
+func NewHandler(service *adminService) *Handler {
+	return &Handler{service: service}
+}
+
+// Indexes reports the state of the index catalog and the builds in progress.
+func (h *Handler) Indexes(c *gin.Context) {
+	report, err := h.service.Indexes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(report))
+}
@@ -0,0 +1,34 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"sirene-importer/csv"
+)
+
+type IndexReport struct {
+	Indexes []csv.IndexInfo  `json:"indexes"`
+	Builds  []csv.IndexBuild `json:"builds"`
+}
+
+type adminService struct {
+	db *sql.DB
+}
+
+func NewAdminService(db *sql.DB) *adminService {
+	return &adminService{db: db}
+}
+
+func (s *adminService) Indexes(ctx context.Context) (*IndexReport, error) {
+	infos, err := csv.IndexStatus(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	builds, err := csv.IndexProgress(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IndexReport{Indexes: infos, Builds: builds}, nil
+}
@@ -0,0 +1,41 @@
+package company
+
+import "sirene-importer/api/models"
+
+// INSEE "statut de diffusion": 'O' is public, 'P' (and the legacy 'N') means
+// the person asked for their identity and street address not to be published.
+const (
+	DIFFUSION_PUBLIC = "O"
+
+	denominationSearchable = "COALESCE(u.statut_diffusion_unite_legale, 'O') = 'O'"
+)
+
+var uniteLegaleProtectedFields = []string{
+	"denomination", "sigle",
+}
+
+var etablissementProtectedFields = []string{
+	"enseigne", "numero_voie", "type_voie", "libelle_voie",
+}
+
+func isRestricted(statutDiffusion string) bool {
+	return statutDiffusion != "" && statutDiffusion != DIFFUSION_PUBLIC
+}
+
+func applyDiffusionRestrictions(c *models.CompanyResult, uniteLegaleStatut, etablissementStatut string) {
+	if isRestricted(uniteLegaleStatut) {
+		c.Denomination = ""
+		c.Sigle = ""
+		c.Restricted = true
+		c.RestrictedFields = append(c.RestrictedFields, uniteLegaleProtectedFields...)
+	}
+
+	if isRestricted(etablissementStatut) {
+		c.Enseigne = ""
+		c.NumeroVoie = ""
+		c.TypeVoie = ""
+		c.LibelleVoie = ""
+		c.Restricted = true
+		c.RestrictedFields = append(c.RestrictedFields, etablissementProtectedFields...)
+	}
+}
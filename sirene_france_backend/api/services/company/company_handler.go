@@ -1,10 +1,15 @@
 package company
 
 import (
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"sirene-importer/api/models"
+	"sirene-importer/identifier"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Handler struct {
@@ -37,15 +42,66 @@ func parseOffset(c *gin.Context) int {
 	return 0
 }
 
+func parseAfter(c *gin.Context) (*models.Cursor, error) {
+	after := c.Query("after")
+	if after == "" {
+		return nil, nil
+	}
+
+	dateCreation, siren, found := strings.Cut(after, ",")
+	if !found || len(siren) != identifier.SIREN_LENGTH || strings.Trim(siren, "0123456789") != "" {
+		return nil, fmt.Errorf("after parameter must be <date_creation>,<siren>")
+	}
+	if err := validateDate("after date_creation", dateCreation); err != nil {
+		return nil, err
+	}
+
+	return &models.Cursor{DateCreation: dateCreation, Siren: siren}, nil
+}
+
+// validateDate rejects a date the DATE comparison in SQL would fail on.
+func validateDate(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		return fmt.Errorf("%s must be a date (YYYY-MM-DD), got %q", name, value)
+	}
+	return nil
+}
+
+func validateDateRange(from, to string) error {
+	if err := validateDate("from", from); err != nil {
+		return err
+	}
+	return validateDate("to", to)
+}
+
+func parsePage(c *gin.Context, defaultLimit int) (models.PageRequest, error) {
+	after, err := parseAfter(c)
+	if err != nil {
+		return models.PageRequest{}, err
+	}
+
+	return models.PageRequest{
+		Limit:  parseLimit(c, defaultLimit),
+		Offset: parseOffset(c),
+		After:  after,
+	}, nil
+}
+
 func (h *Handler) SearchByNafCode(c *gin.Context) {
 	code := c.Query("code")
 	if code == "" {
 		c.JSON(http.StatusBadRequest, models.Error("code parameter required"))
 		return
 	}
-	limit := parseLimit(c, 100)
-	offset := parseOffset(c)
-	result, err := h.service.SearchByNafCode(c.Request.Context(), code, limit, offset)
+	page, err := parsePage(c, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
+		return
+	}
+	result, err := h.service.SearchByNafCode(c.Request.Context(), code, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
 		return
@@ -59,9 +115,44 @@ func (h *Handler) SearchByDenomination(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.Error("q parameter required"))
 		return
 	}
-	limit := parseLimit(c, 100)
-	offset := parseOffset(c)
-	result, err := h.service.SearchByDenomination(c.Request.Context(), query, limit, offset)
+	page, err := parsePage(c, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
+		return
+	}
+	result, err := h.service.SearchByDenomination(c.Request.Context(), query, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
+func (h *Handler) SearchByName(c *gin.Context) {
+	name := c.Query("q")
+	if strings.TrimSpace(name) == "" {
+		c.JSON(http.StatusBadRequest, models.Error("q parameter required"))
+		return
+	}
+	result, err := h.service.SearchByName(c.Request.Context(), name, parseLimit(c, 20), parseOffset(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
+func (h *Handler) SearchFullText(c *gin.Context) {
+	q := c.Query("q")
+	if strings.TrimSpace(q) == "" {
+		c.JSON(http.StatusBadRequest, models.Error("q parameter required"))
+		return
+	}
+	if len(strings.Fields(q)) > MAX_FULLTEXT_TERMS {
+		c.JSON(http.StatusBadRequest, models.Error(fmt.Sprintf("q must have at most %d words", MAX_FULLTEXT_TERMS)))
+		return
+	}
+	result, err := h.service.SearchFullText(c.Request.Context(), q, parseLimit(c, 20), parseOffset(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
 		return
@@ -69,15 +160,32 @@ func (h *Handler) SearchByDenomination(c *gin.Context) {
 	c.JSON(http.StatusOK, models.Success(result))
 }
 
+func (h *Handler) Suggest(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if len([]rune(q)) < MIN_SUGGEST_LENGTH {
+		c.JSON(http.StatusBadRequest, models.Error(fmt.Sprintf("q must have at least %d characters", MIN_SUGGEST_LENGTH)))
+		return
+	}
+	suggestions, err := h.service.Suggest(c.Request.Context(), q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessWithMeta(suggestions, models.Meta{Count: len(suggestions)}))
+}
+
 func (h *Handler) SearchByCodePostal(c *gin.Context) {
 	cp := c.Query("q")
 	if cp == "" {
 		c.JSON(http.StatusBadRequest, models.Error("q parameter required"))
 		return
 	}
-	limit := parseLimit(c, 100)
-	offset := parseOffset(c)
-	result, err := h.service.SearchByCodePostal(c.Request.Context(), cp, limit, offset)
+	page, err := parsePage(c, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
+		return
+	}
+	result, err := h.service.SearchByCodePostal(c.Request.Context(), cp, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
 		return
@@ -88,13 +196,20 @@ func (h *Handler) SearchByCodePostal(c *gin.Context) {
 func (h *Handler) SearchByDateCreation(c *gin.Context) {
 	from := c.Query("from")
 	to := c.Query("to")
-	if from == "" {
-		c.JSON(http.StatusBadRequest, models.Error("from parameter required"))
+	if from == "" && to == "" {
+		c.JSON(http.StatusBadRequest, models.Error("from or to parameter required (YYYY-MM-DD)"))
+		return
+	}
+	if err := validateDateRange(from, to); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
+		return
+	}
+	page, err := parsePage(c, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
 		return
 	}
-	limit := parseLimit(c, 100)
-	offset := parseOffset(c)
-	result, err := h.service.SearchByDateCreation(c.Request.Context(), from, to, limit, offset)
+	result, err := h.service.SearchByDateCreation(c.Request.Context(), from, to, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
 		return
@@ -108,9 +223,12 @@ func (h *Handler) SearchByCommune(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.Error("q parameter required"))
 		return
 	}
-	limit := parseLimit(c, 100)
-	offset := parseOffset(c)
-	result, err := h.service.SearchByCommune(c.Request.Context(), commune, limit, offset)
+	page, err := parsePage(c, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
+		return
+	}
+	result, err := h.service.SearchByCommune(c.Request.Context(), commune, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
 		return
@@ -124,9 +242,12 @@ func (h *Handler) SearchByEtatAdministratif(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.Error("q parameter required"))
 		return
 	}
-	limit := parseLimit(c, 100)
-	offset := parseOffset(c)
-	result, err := h.service.SearchByEtatAdministratif(c.Request.Context(), etat, limit, offset)
+	page, err := parsePage(c, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
+		return
+	}
+	result, err := h.service.SearchByEtatAdministratif(c.Request.Context(), etat, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
 		return
@@ -148,9 +269,16 @@ func (h *Handler) SearchMultiCriteria(c *gin.Context) {
 		CategorieJuridique: c.Query("categorie_juridique"),
 		TrancheEffectifs:   c.Query("tranche_effectifs"),
 	}
-	limit := parseLimit(c, 100)
-	offset := parseOffset(c)
-	result, err := h.service.SearchMultiCriteria(c.Request.Context(), criteria, limit, offset)
+	if err := validateDateRange(criteria.DateCreationFrom, criteria.DateCreationTo); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
+		return
+	}
+	page, err := parsePage(c, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
+		return
+	}
+	result, err := h.service.SearchMultiCriteria(c.Request.Context(), criteria, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
 		return
@@ -159,15 +287,74 @@ func (h *Handler) SearchMultiCriteria(c *gin.Context) {
 }
 
 func (h *Handler) SearchByIdentifier(c *gin.Context) {
-	identifier := c.Param("identifier")
-	if identifier == "" {
+	if c.Param("identifier") == "" {
 		c.JSON(http.StatusBadRequest, models.Error("identifier parameter required (SIREN 9 digits or SIRET 14 digits)"))
 		return
 	}
-	result, err := h.service.SearchByIdentifier(c.Request.Context(), identifier)
+	_, number, err := identifier.Normalize(c.Param("identifier"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
 		return
 	}
+	result, err := h.service.SearchByIdentifier(c.Request.Context(), number)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
+		return
+	}
 	c.JSON(http.StatusOK, models.Success(result))
 }
+
+func (h *Handler) GetCompanyProfile(c *gin.Context) {
+	siren, err := identifier.NormalizeSiren(c.Param("siren"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
+		return
+	}
+
+	etat := strings.ToUpper(c.Query("etat"))
+	if etat != "" && etat != "A" && etat != "F" {
+		c.JSON(http.StatusBadRequest, models.Error("etat must be A (ouvert) or F (ferme)"))
+		return
+	}
+
+	company, meta, err := h.service.GetCompanyProfile(c.Request.Context(), siren, etat, parseLimit(c, 50), parseOffset(c))
+	if errors.Is(err, ErrCompanyNotFound) {
+		c.JSON(http.StatusNotFound, models.Error("siren "+siren+" not found"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessWithMeta(company, meta))
+}
+
+func (h *Handler) LookupIdentifiers(c *gin.Context) {
+	var request models.LookupRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error("invalid request body: expected {\"identifiers\": [...]}"))
+		return
+	}
+	if len(request.Identifiers) == 0 {
+		c.JSON(http.StatusBadRequest, models.Error("identifiers must contain at least one SIREN or SIRET"))
+		return
+	}
+	if len(request.Identifiers) > MAX_LOOKUP_IDENTIFIERS {
+		c.JSON(http.StatusBadRequest, models.Error(fmt.Sprintf("at most %d identifiers per lookup", MAX_LOOKUP_IDENTIFIERS)))
+		return
+	}
+	results, err := h.service.LookupIdentifiers(c.Request.Context(), request.Identifiers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
+		return
+	}
+
+	found := 0
+	for _, result := range results {
+		if result.Status == models.LOOKUP_FOUND {
+			found++
+		}
+	}
+	c.JSON(http.StatusOK, models.SuccessWithMeta(results, models.Meta{Count: len(results), Total: found}))
+}
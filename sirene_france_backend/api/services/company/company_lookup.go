@@ -0,0 +1,104 @@
+package company
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sirene-importer/api/models"
+	"sirene-importer/identifier"
+)
+
+const MAX_LOOKUP_IDENTIFIERS = 1000
+
+// LookupIdentifiers resolves a batch of SIREN and SIRET in request order,
+// with one query for all the SIREN and one for all the SIRET.
+func (s *companyService) LookupIdentifiers(ctx context.Context, inputs []string) ([]models.LookupResult, error) {
+	results := make([]models.LookupResult, len(inputs))
+	var sirens, sirets []string
+
+	for i, input := range inputs {
+		results[i].Input = input
+		kind, number, err := identifier.Normalize(input)
+		if err != nil {
+			results[i].Status = models.LOOKUP_INVALID
+			results[i].Error = err.Error()
+			continue
+		}
+
+		results[i].Kind = kind
+		results[i].Identifier = number
+		if kind == identifier.KIND_SIRET {
+			sirets = append(sirets, number)
+		} else {
+			sirens = append(sirens, number)
+		}
+	}
+
+	bySiren, err := s.lookupCompanies(ctx, "DISTINCT ON (u.siren)", "e.etablissement_siege AND u.siren = ANY($1)", "u.siren", sirens)
+	if err != nil {
+		return nil, err
+	}
+	bySiret, err := s.lookupCompanies(ctx, "DISTINCT ON (e.siret)", "e.siret = ANY($1)", "e.siret", sirets)
+	if err != nil {
+		return nil, err
+	}
+
+	found := 0
+	for i := range results {
+		result := &results[i]
+		if result.Status == models.LOOKUP_INVALID {
+			continue
+		}
+
+		companies := bySiren
+		if result.Kind == identifier.KIND_SIRET {
+			companies = bySiret
+		}
+		company, ok := companies[result.Identifier]
+		if !ok {
+			result.Status = models.LOOKUP_NOT_FOUND
+			continue
+		}
+		result.Status = models.LOOKUP_FOUND
+		result.Company = &company
+		found++
+	}
+
+	slog.Info("Lookup finished", "requested", len(inputs), "found", found)
+	return results, nil
+}
+
+// lookupCompanies loads the companies matching numbers, keyed by the column
+// they were looked up by.
+func (s *companyService) lookupCompanies(ctx context.Context, distinct, where, key string, numbers []string) (map[string]models.CompanyResult, error) {
+	companies := make(map[string]models.CompanyResult, len(numbers))
+	if len(numbers) == 0 {
+		return companies, nil
+	}
+
+	query := fmt.Sprintf(`SELECT %s %s
+		FROM etablissement e
+		JOIN unite_legale u ON e.siren = u.siren
+		LEFT JOIN naf_reference naf ON COALESCE(NULLIF(e.activite_principale_etablissement, ''), u.activite_principale_unite_legale, '') = naf.code
+		WHERE %s
+		ORDER BY %s`, distinct, companySelectFields, where, key)
+
+	rows, err := s.db.QueryContext(ctx, query, numbers)
+	if err != nil {
+		return nil, fmt.Errorf("lookup failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		c, err := scanCompanyRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("lookup scan failed: %w", err)
+		}
+		if key == "e.siret" {
+			companies[c.Siret] = c
+		} else {
+			companies[c.Siren] = c
+		}
+	}
+	return companies, rows.Err()
+}
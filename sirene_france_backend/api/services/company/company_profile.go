@@ -0,0 +1,243 @@
+package company
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sirene-importer/api/models"
+	"strings"
+	"time"
+)
+
+var ErrCompanyNotFound = errors.New("company not found")
+
+// Columns of the StockUniteLegale file returned by the profile. The table also
+// holds search_vector, which is internal and never exposed.
+var uniteLegaleColumns = []string{
+	"siren", "statut_diffusion_unite_legale", "unite_purgee_unite_legale", "date_creation_unite_legale",
+	"sigle_unite_legale", "sexe_unite_legale",
+	"prenom1_unite_legale", "prenom2_unite_legale", "prenom3_unite_legale", "prenom4_unite_legale",
+	"prenom_usuel_unite_legale", "pseudonyme_unite_legale", "identifiant_association_unite_legale",
+	"tranche_effectifs_unite_legale", "annee_effectifs_unite_legale", "date_dernier_traitement_unite_legale",
+	"nombre_periodes_unite_legale", "categorie_entreprise", "annee_categorie_entreprise", "date_debut",
+	"etat_administratif_unite_legale", "nom_unite_legale", "nom_usage_unite_legale", "denomination_unite_legale",
+	"denomination_usuelle1_unite_legale", "denomination_usuelle2_unite_legale", "denomination_usuelle3_unite_legale",
+	"categorie_juridique_unite_legale", "activite_principale_unite_legale",
+	"nomenclature_activite_principale_unite_legale", "activite_principale_n_a_f25_unite_legale",
+	"nic_siege_unite_legale", "economie_sociale_solidaire_unite_legale", "societe_mission_unite_legale",
+	"caractere_employeur_unite_legale",
+}
+
+// Columns of the raw unite_legale record that identify a person and are
+// withheld when the unite legale is not publicly diffusible.
+var uniteLegaleProtectedColumns = []string{
+	"denomination_unite_legale", "sigle_unite_legale", "nom_unite_legale", "nom_usage_unite_legale",
+	"prenom1_unite_legale", "prenom2_unite_legale", "prenom3_unite_legale", "prenom4_unite_legale",
+	"prenom_usuel_unite_legale", "pseudonyme_unite_legale", "sexe_unite_legale",
+	"denomination_usuelle1_unite_legale", "denomination_usuelle2_unite_legale", "denomination_usuelle3_unite_legale",
+}
+
+const etablissementListFields = `
+	COALESCE(e.siret, ''),
+	COALESCE(e.nic, ''),
+	COALESCE(e.etablissement_siege, false),
+	COALESCE(e.etat_administratif_etablissement, ''),
+	COALESCE(TO_CHAR(e.date_creation_etablissement, 'YYYY-MM-DD'), ''),
+	COALESCE(e.enseigne1_etablissement, ''),
+	COALESCE(e.denomination_usuelle_etablissement, ''),
+	COALESCE(e.numero_voie_etablissement, ''),
+	COALESCE(e.type_voie_etablissement, ''),
+	COALESCE(e.libelle_voie_etablissement, ''),
+	COALESCE(e.complement_adresse_etablissement, ''),
+	COALESCE(e.code_postal_etablissement, ''),
+	COALESCE(e.libelle_commune_etablissement, ''),
+	COALESCE(e.activite_principale_etablissement, ''),
+	COALESCE(naf.label, ''),
+	COALESCE(e.tranche_effectifs_etablissement, ''),
+	COALESCE(e.statut_diffusion_etablissement, '')`
+
+// GetCompanyProfile returns the head office result of a SIREN with the full
+// unite legale record and one page of its establishments, open and closed,
+// head office first. etat filters establishments on A (open) or F (closed).
+func (s *companyService) GetCompanyProfile(ctx context.Context, siren, etat string, limit, offset int) (*models.CompanyResult, models.Meta, error) {
+	type cachedProfile struct {
+		Company models.CompanyResult
+		Meta    models.Meta
+	}
+
+	cacheKey := fmt.Sprintf("companies:profile:%s:%s:l%d:o%d", siren, etat, limit, offset)
+	var cached cachedProfile
+	if err := s.cache.Get(cacheKey, &cached); err == nil {
+		return &cached.Company, cached.Meta, nil
+	}
+
+	uniteLegale, err := s.loadUniteLegale(ctx, siren)
+	if err != nil {
+		return nil, models.Meta{}, err
+	}
+
+	query := fmt.Sprintf(`SELECT %s
+		FROM unite_legale u
+		LEFT JOIN etablissement e ON e.siren = u.siren AND e.etablissement_siege
+		LEFT JOIN naf_reference naf ON COALESCE(NULLIF(e.activite_principale_etablissement, ''), u.activite_principale_unite_legale, '') = naf.code
+		WHERE u.siren = $1
+		LIMIT 1`, companySelectFields)
+
+	company, err := scanCompanyRow(s.db.QueryRowContext(ctx, query, siren))
+	if err != nil {
+		return nil, models.Meta{}, fmt.Errorf("profile query failed: %w", err)
+	}
+	company.UniteLegale = uniteLegale
+
+	conditions := "e.siren = $1"
+	args := []any{siren}
+	if etat != "" {
+		conditions += " AND e.etat_administratif_etablissement = $2"
+		args = append(args, etat)
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM etablissement e WHERE "+conditions, args...).Scan(&total); err != nil {
+		return nil, models.Meta{}, fmt.Errorf("count etablissements failed: %w", err)
+	}
+
+	listQuery := fmt.Sprintf(`SELECT %s
+		FROM etablissement e
+		LEFT JOIN naf_reference naf ON e.activite_principale_etablissement = naf.code
+		WHERE %s
+		ORDER BY e.etablissement_siege DESC NULLS LAST, e.etat_administratif_etablissement = 'F', e.siret
+		LIMIT $%d OFFSET $%d`, etablissementListFields, conditions, len(args)+1, len(args)+2)
+
+	rows, err := s.db.QueryContext(ctx, listQuery, append(args, limit, offset)...)
+	if err != nil {
+		return nil, models.Meta{}, fmt.Errorf("etablissements query failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	company.Etablissements = []map[string]any{}
+	for rows.Next() {
+		etablissement, err := scanEtablissementRow(rows)
+		if err != nil {
+			return nil, models.Meta{}, fmt.Errorf("etablissements scan failed: %w", err)
+		}
+		company.Etablissements = append(company.Etablissements, etablissement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.Meta{}, fmt.Errorf("rows error: %w", err)
+	}
+
+	meta := models.Meta{Total: total, Count: len(company.Etablissements), Limit: limit, Offset: offset}
+	if limit > 0 {
+		meta.Page = (offset / limit) + 1
+		meta.Pages = (total + limit - 1) / limit
+	}
+
+	if err := s.cache.Set(cacheKey, cachedProfile{Company: company, Meta: meta}, 1*time.Hour); err != nil {
+		slog.Error("Cache write failed", "key", cacheKey, "error", err.Error())
+	}
+	return &company, meta, nil
+}
+
+// loadUniteLegale reads the uniteLegaleColumns of the unite legale, dates as
+// YYYY-MM-DD, with the identity columns withheld when it is not diffusible.
+func (s *companyService) loadUniteLegale(ctx context.Context, siren string) (map[string]any, error) {
+	query := "SELECT " + strings.Join(uniteLegaleColumns, ", ") + " FROM unite_legale WHERE siren = $1"
+	rows, err := s.db.QueryContext(ctx, query, siren)
+	if err != nil {
+		return nil, fmt.Errorf("unite legale query failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("unite legale query failed: %w", err)
+		}
+		return nil, ErrCompanyNotFound
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("unite legale scan failed: %w", err)
+	}
+
+	record := make(map[string]any, len(columns))
+	for i, column := range columns {
+		switch v := values[i].(type) {
+		case time.Time:
+			if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 {
+				record[column] = v.Format("2006-01-02")
+			} else {
+				record[column] = v.Format("2006-01-02T15:04:05")
+			}
+		case []byte:
+			record[column] = string(v)
+		default:
+			record[column] = v
+		}
+	}
+
+	if statut, _ := record["statut_diffusion_unite_legale"].(string); isRestricted(statut) {
+		for _, column := range uniteLegaleProtectedColumns {
+			if _, ok := record[column]; ok {
+				record[column] = nil
+			}
+		}
+	}
+
+	return record, nil
+}
+
+func scanEtablissementRow(scanner interface{ Scan(...any) error }) (map[string]any, error) {
+	var siret, nic, etat, dateCreation, enseigne, denominationUsuelle string
+	var numeroVoie, typeVoie, libelleVoie, complementAdresse, codePostal, libelleCommune string
+	var nafCode, nafLabel, trancheEffectifs, statutDiffusion string
+	var siege bool
+
+	err := scanner.Scan(&siret, &nic, &siege, &etat, &dateCreation, &enseigne, &denominationUsuelle,
+		&numeroVoie, &typeVoie, &libelleVoie, &complementAdresse, &codePostal, &libelleCommune,
+		&nafCode, &nafLabel, &trancheEffectifs, &statutDiffusion)
+	if err != nil {
+		return nil, err
+	}
+
+	etablissement := map[string]any{
+		"siret":                siret,
+		"nic":                  nic,
+		"siege":                siege,
+		"etat_administratif":   etat,
+		"date_creation":        dateCreation,
+		"enseigne":             enseigne,
+		"denomination_usuelle": denominationUsuelle,
+		"numero_voie":          numeroVoie,
+		"type_voie":            typeVoie,
+		"libelle_voie":         libelleVoie,
+		"complement_adresse":   complementAdresse,
+		"code_postal":          codePostal,
+		"libelle_commune":      libelleCommune,
+		"naf_code":             nafCode,
+		"naf_label":            nafLabel,
+		"tranche_effectifs":    trancheEffectifs,
+		"restricted":           false,
+	}
+
+	if isRestricted(statutDiffusion) {
+		for _, field := range etablissementProtectedFields {
+			etablissement[field] = ""
+		}
+		etablissement["denomination_usuelle"] = ""
+		etablissement["complement_adresse"] = ""
+		etablissement["restricted"] = true
+	}
+
+	return etablissement, nil
+}
@@ -15,7 +15,7 @@ const companySelectFields = `
 	COALESCE(u.denomination_unite_legale, ''),
 	COALESCE(u.sigle_unite_legale, ''),
 	COALESCE(u.categorie_juridique_unite_legale, ''),
-	COALESCE(u.date_creation_unite_legale, ''),
+	COALESCE(TO_CHAR(u.date_creation_unite_legale, 'YYYY-MM-DD'), ''),
 	COALESCE(u.etat_administratif_unite_legale, ''),
 	COALESCE(u.tranche_effectifs_unite_legale, ''),
 	COALESCE(u.categorie_entreprise, ''),
@@ -27,11 +27,13 @@ const companySelectFields = `
 	COALESCE(e.code_postal_etablissement, ''),
 	COALESCE(e.libelle_commune_etablissement, ''),
 	COALESCE(NULLIF(e.activite_principale_etablissement, ''), u.activite_principale_unite_legale, ''),
-	COALESCE(naf.label, '')`
-
+	COALESCE(naf.label, ''),
+	COALESCE(u.statut_diffusion_unite_legale, ''),
+	COALESCE(e.statut_diffusion_etablissement, '')`
 
 func scanCompanyRow(scanner interface{ Scan(...any) error }) (models.CompanyResult, error) {
 	var c models.CompanyResult
+	var uniteLegaleStatut, etablissementStatut string
 	err := scanner.Scan(
 		&c.Siren, &c.Denomination, &c.Sigle, &c.CategorieJuridique,
 		&c.DateCreation, &c.EtatAdministratif, &c.TrancheEffectifs,
@@ -39,32 +41,55 @@ func scanCompanyRow(scanner interface{ Scan(...any) error }) (models.CompanyResu
 		&c.Siret, &c.Enseigne, &c.NumeroVoie, &c.TypeVoie,
 		&c.LibelleVoie, &c.CodePostal, &c.LibelleCommune,
 		&c.NafCode, &c.NafLabel,
+		&uniteLegaleStatut, &etablissementStatut,
 	)
-	return c, err
+	if err != nil {
+		return c, err
+	}
+	applyDiffusionRestrictions(&c, uniteLegaleStatut, etablissementStatut)
+	return c, nil
 }
 
-func (s *companyService) searchCompanies(ctx context.Context, conditions []string, args []any, limit, offset int, cacheKey string, criteria models.CompanySearchCriteria) (*models.CompanySearchResult, error) {
-	pageCacheKey := fmt.Sprintf("%s:l%d:o%d", cacheKey, limit, offset)
+// Companies without a creation date sort last, after every dated one.
+const keysetDate = "COALESCE(u.date_creation_unite_legale, DATE '0001-01-01')"
+
+const keysetOrder = keysetDate + " DESC, u.siren DESC"
+
+func (s *companyService) searchCompanies(ctx context.Context, conditions []string, args []any, page models.PageRequest, cacheKey string, criteria models.CompanySearchCriteria) (*models.CompanySearchResult, error) {
+	limit, offset := page.Limit, page.Offset
+	after := ""
+	if page.After != nil {
+		offset = 0
+		after = page.After.String()
+	}
+
+	pageCacheKey := fmt.Sprintf("%s:l%d:o%d:a%s", cacheKey, limit, offset, after)
 	var cached models.CompanySearchResult
 	if err := s.cache.Get(pageCacheKey, &cached); err == nil {
 		return &cached, nil
 	}
 
 	where := "WHERE " + strings.Join(conditions, " AND ")
-	argN := len(args) + 1
+
+	dataArgs := make([]any, len(args), len(args)+4)
+	copy(dataArgs, args)
+	dataWhere := where
+	if page.After != nil {
+		dataWhere += fmt.Sprintf(" AND (%s, u.siren) < (COALESCE(NULLIF($%d, '')::date, DATE '0001-01-01'), $%d)",
+			keysetDate, len(dataArgs)+1, len(dataArgs)+2)
+		dataArgs = append(dataArgs, page.After.DateCreation, page.After.Siren)
+	}
+	argN := len(dataArgs) + 1
 
 	dataQuery := fmt.Sprintf(`SELECT %s
 		FROM etablissement e
 		JOIN unite_legale u ON e.siren = u.siren
 		LEFT JOIN naf_reference naf ON COALESCE(NULLIF(e.activite_principale_etablissement, ''), u.activite_principale_unite_legale, '') = naf.code
 		%s
-		ORDER BY u.date_creation_unite_legale DESC
-		LIMIT $%d OFFSET $%d`, companySelectFields, where, argN, argN+1)
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d`, companySelectFields, dataWhere, keysetOrder, argN, argN+1)
 
-	dataArgs := make([]any, len(args)+2)
-	copy(dataArgs, args)
-	dataArgs[len(args)] = limit
-	dataArgs[len(args)+1] = offset
+	dataArgs = append(dataArgs, limit+1, offset)
 
 	countCacheKey := cacheKey + ":count"
 	var totalCount int
@@ -101,11 +126,11 @@ func (s *companyService) searchCompanies(ctx context.Context, conditions []strin
 	}
 	defer func() { _ = rows.Close() }()
 
-	companies := make([]models.CompanyResult, 0, limit)
+	companies := make([]models.CompanyResult, 0, limit+1)
 	for rows.Next() {
 		c, err := scanCompanyRow(rows)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("search scan failed: %w", err)
 		}
 		companies = append(companies, c)
 	}
@@ -113,6 +138,13 @@ func (s *companyService) searchCompanies(ctx context.Context, conditions []strin
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
 
+	nextCursor := ""
+	if len(companies) > limit {
+		companies = companies[:limit]
+		last := companies[len(companies)-1]
+		nextCursor = models.Cursor{DateCreation: last.DateCreation, Siren: last.Siren}.String()
+	}
+
 	if !countCached {
 		wg.Wait()
 		if countErr != nil {
@@ -121,9 +153,9 @@ func (s *companyService) searchCompanies(ctx context.Context, conditions []strin
 		}
 	}
 
-	page := 1
-	if limit > 0 {
-		page = (offset / limit) + 1
+	pageNumber := 0
+	if limit > 0 && page.After == nil {
+		pageNumber = (offset / limit) + 1
 	}
 	pages := 0
 	if limit > 0 && totalCount > 0 {
@@ -134,12 +166,13 @@ func (s *companyService) searchCompanies(ctx context.Context, conditions []strin
 		Criteria: criteria,
 		Results:  companies,
 		Meta: models.Meta{
-			Total:  totalCount,
-			Count:  len(companies),
-			Limit:  limit,
-			Offset: offset,
-			Page:   page,
-			Pages:  pages,
+			Total:      totalCount,
+			Count:      len(companies),
+			Limit:      limit,
+			Offset:     offset,
+			Page:       pageNumber,
+			Pages:      pages,
+			NextCursor: nextCursor,
 		},
 	}
 
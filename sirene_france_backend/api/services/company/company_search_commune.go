@@ -6,14 +6,14 @@ import (
 	"sirene-importer/api/models"
 )
 
-func (s *companyService) SearchByCommune(ctx context.Context, commune string, limit, offset int) (*models.CompanySearchResult, error) {
+func (s *companyService) SearchByCommune(ctx context.Context, commune string, page models.PageRequest) (*models.CompanySearchResult, error) {
 	conditions := []string{
-		"e.etablissement_siege = 'true'",
+		"e.etablissement_siege",
 		"immutable_unaccent(e.libelle_commune_etablissement) ILIKE immutable_unaccent($1)",
 	}
 	args := []any{"%" + commune + "%"}
-	cacheKey := fmt.Sprintf("sirene:v2:commune:%s", commune)
+	cacheKey := fmt.Sprintf("sirene:v3:commune:%s", commune)
 	criteria := models.CompanySearchCriteria{Commune: commune}
 
-	return s.searchCompanies(ctx, conditions, args, limit, offset, cacheKey, criteria)
+	return s.searchCompanies(ctx, conditions, args, page, cacheKey, criteria)
 }
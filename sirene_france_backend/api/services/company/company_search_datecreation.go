@@ -6,22 +6,26 @@ import (
 	"sirene-importer/api/models"
 )
 
-func (s *companyService) SearchByDateCreation(ctx context.Context, fromDate, toDate string, limit int, offset int) (*models.CompanySearchResult, error) {
-	conditions := []string{"e.etablissement_siege = 'true'"}
+func (s *companyService) SearchByDateCreation(ctx context.Context, fromDate, toDate string, page models.PageRequest) (*models.CompanySearchResult, error) {
+	if fromDate == "" && toDate == "" {
+		return nil, fmt.Errorf("date_creation from or to is required")
+	}
+
+	conditions := []string{"e.etablissement_siege"}
 	var args []any
-	argN := 1
 
-	conditions = append(conditions, fmt.Sprintf("u.date_creation_unite_legale >= $%d", argN))
-	args = append(args, fromDate)
-	argN++
+	if fromDate != "" {
+		args = append(args, fromDate)
+		conditions = append(conditions, fmt.Sprintf("u.date_creation_unite_legale >= $%d", len(args)))
+	}
 
 	if toDate != "" {
-		conditions = append(conditions, fmt.Sprintf("u.date_creation_unite_legale <= $%d", argN))
 		args = append(args, toDate)
+		conditions = append(conditions, fmt.Sprintf("u.date_creation_unite_legale <= $%d", len(args)))
 	}
 
-	cacheKey := fmt.Sprintf("sirene:v2:datecreation:%s_%s", fromDate, toDate)
+	cacheKey := fmt.Sprintf("sirene:v3:datecreation:%s_%s", fromDate, toDate)
 	criteria := models.CompanySearchCriteria{DateCreationFrom: fromDate, DateCreationTo: toDate}
 
-	return s.searchCompanies(ctx, conditions, args, limit, offset, cacheKey, criteria)
+	return s.searchCompanies(ctx, conditions, args, page, cacheKey, criteria)
 }
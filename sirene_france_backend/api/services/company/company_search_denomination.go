@@ -7,7 +7,7 @@ import (
 	"strings"
 )
 
-func (s *companyService) SearchByDenomination(ctx context.Context, query string, limit, offset int) (*models.CompanySearchResult, error) {
+func (s *companyService) SearchByDenomination(ctx context.Context, query string, page models.PageRequest) (*models.CompanySearchResult, error) {
 	words := strings.Fields(strings.TrimSpace(query))
 	if len(words) == 0 {
 		return &models.CompanySearchResult{
@@ -17,7 +17,7 @@ func (s *companyService) SearchByDenomination(ctx context.Context, query string,
 		}, nil
 	}
 
-	conditions := []string{"e.etablissement_siege = 'true'"}
+	conditions := []string{"e.etablissement_siege", denominationSearchable}
 	var args []any
 	argN := 1
 
@@ -27,8 +27,8 @@ func (s *companyService) SearchByDenomination(ctx context.Context, query string,
 		argN++
 	}
 
-	cacheKey := fmt.Sprintf("sirene:v2:denomination:%s", query)
+	cacheKey := fmt.Sprintf("sirene:v3:denomination:%s", query)
 	criteria := models.CompanySearchCriteria{Denomination: query}
 
-	return s.searchCompanies(ctx, conditions, args, limit, offset, cacheKey, criteria)
+	return s.searchCompanies(ctx, conditions, args, page, cacheKey, criteria)
 }
@@ -0,0 +1,148 @@
+package company
+
+import (
+	"context"
+	"fmt"
+	"sirene-importer/api/models"
+	"strings"
+	"time"
+)
+
+const (
+	MAX_FULLTEXT_LIMIT = 100
+
+	// Every split of the words between the unite legale and the
+	// etablissement is searched, so the number of words is capped.
+	MAX_FULLTEXT_TERMS = 4
+
+	etablissementSearchable = "COALESCE(e.statut_diffusion_etablissement, 'O') = 'O'"
+)
+
+// fulltextSplits returns one subquery per way of sharing the words between
+// the search_vector of unite_legale and the one of etablissement, so
+// "boulangerie martin lyon" finds a MARTIN unite legale whose establishment
+// has a boulangerie sign in Lyon. Each side uses its own GIN index; a side
+// withheld from diffusion is never matched.
+func fulltextSplits(words []string) ([]string, []any) {
+	var subqueries []string
+	var args []any
+	match := func(alias string, terms []string) string {
+		args = append(args, strings.Join(terms, " "))
+		return fmt.Sprintf("%s.search_vector @@ plainto_tsquery('french', $%d)", alias, len(args))
+	}
+
+	for mask := 0; mask < 1<<len(words); mask++ {
+		var ulWords, etabWords []string
+		for i, word := range words {
+			if mask&(1<<i) != 0 {
+				ulWords = append(ulWords, word)
+			} else {
+				etabWords = append(etabWords, word)
+			}
+		}
+
+		switch {
+		case len(etabWords) == 0:
+			subqueries = append(subqueries, fmt.Sprintf(`SELECT e.siret FROM unite_legale u
+				JOIN etablissement e ON e.siren = u.siren AND e.etablissement_siege
+				WHERE %s AND %s`, match("u", ulWords), denominationSearchable))
+		case len(ulWords) == 0:
+			subqueries = append(subqueries, fmt.Sprintf(`SELECT e.siret FROM etablissement e
+				WHERE %s AND %s`, match("e", etabWords), etablissementSearchable))
+		default:
+			ul, etab := match("u", ulWords), match("e", etabWords)
+			subqueries = append(subqueries, fmt.Sprintf(`SELECT e.siret FROM unite_legale u
+				JOIN etablissement e ON e.siren = u.siren
+				WHERE %s AND %s AND %s AND %s`, ul, etab, denominationSearchable, etablissementSearchable))
+		}
+	}
+	return subqueries, args
+}
+
+// SearchFullText matches the words of q against the French search_vector
+// columns (denomination, sigle, usual names, signs, commune) and ranks the
+// establishments with ts_rank over the visible fields. Highlight is a
+// ts_headline snippet with the matched words wrapped in <mark>.
+func (s *companyService) SearchFullText(ctx context.Context, q string, limit, offset int) (*models.CompanySearchResult, error) {
+	criteria := models.CompanySearchCriteria{Query: q}
+	words := strings.Fields(strings.ToLower(q))
+	if len(words) > MAX_FULLTEXT_TERMS {
+		words = words[:MAX_FULLTEXT_TERMS]
+	}
+	limit = min(limit, MAX_FULLTEXT_LIMIT)
+
+	cacheKey := fmt.Sprintf("sirene:v3:fts:%s:l%d:o%d", strings.Join(words, " "), limit, offset)
+	var cached models.CompanySearchResult
+	if err := s.cache.Get(cacheKey, &cached); err == nil {
+		return &cached, nil
+	}
+
+	subqueries, args := fulltextSplits(words)
+	args = append(args, strings.Join(words, " "), limit, offset)
+	queryArg, limitArg, offsetArg := len(args)-2, len(args)-1, len(args)
+
+	dataQuery := fmt.Sprintf(`WITH hits AS (
+			%s
+		),
+		ranked AS (
+			SELECT e.siret,
+				ts_rank(
+					CASE WHEN %s THEN u.search_vector ELSE ''::tsvector END ||
+					CASE WHEN %s THEN e.search_vector ELSE ''::tsvector END,
+					plainto_tsquery('french', $%d)
+				)::float8 AS rank,
+				COUNT(*) OVER () AS total
+			FROM hits h
+			JOIN etablissement e ON e.siret = h.siret
+			JOIN unite_legale u ON u.siren = e.siren
+			ORDER BY rank DESC, e.etablissement_siege DESC, e.siret
+			LIMIT $%d OFFSET $%d
+		)
+		SELECT %s, round(r.rank::numeric, 4)::float8, r.total,
+			ts_headline('french',
+				concat_ws(' - ',
+					CASE WHEN %s THEN concat_ws(' ', u.denomination_unite_legale, u.sigle_unite_legale, u.denomination_usuelle1_unite_legale) END,
+					CASE WHEN %s THEN concat_ws(' ', e.enseigne1_etablissement, e.denomination_usuelle_etablissement) END,
+					e.libelle_commune_etablissement),
+				plainto_tsquery('french', $%d),
+				'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')
+		FROM ranked r
+		JOIN etablissement e ON e.siret = r.siret
+		JOIN unite_legale u ON u.siren = e.siren
+		LEFT JOIN naf_reference naf ON COALESCE(NULLIF(e.activite_principale_etablissement, ''), u.activite_principale_unite_legale, '') = naf.code
+		ORDER BY r.rank DESC, e.etablissement_siege DESC, e.siret`,
+		strings.Join(subqueries, "\n\t\t\tUNION\n\t\t\t"),
+		denominationSearchable, etablissementSearchable, queryArg, limitArg, offsetArg,
+		companySelectFields, denominationSearchable, etablissementSearchable, queryArg)
+
+	rows, err := s.db.QueryContext(ctx, dataQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("full-text search failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	companies := make([]models.CompanyResult, 0, limit)
+	total := 0
+	for rows.Next() {
+		var rank float64
+		var highlight string
+		c, err := scanCompanyRow(scoredRow{rows: rows, extra: []any{&rank, &total, &highlight}})
+		if err != nil {
+			return nil, fmt.Errorf("full-text search scan failed: %w", err)
+		}
+		c.Score = rank
+		c.Highlight = highlight
+		companies = append(companies, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	result := &models.CompanySearchResult{
+		Criteria: criteria,
+		Results:  companies,
+		Meta:     models.Meta{Count: len(companies), Total: total, Limit: limit, Offset: offset},
+	}
+	_ = s.cache.Set(cacheKey, result, 1*time.Hour)
+	return result, nil
+}
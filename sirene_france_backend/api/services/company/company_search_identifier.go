@@ -5,19 +5,17 @@ import (
 	"database/sql"
 	"fmt"
 	"sirene-importer/api/models"
+	"sirene-importer/identifier"
 )
 
-const (
-	SIREN_LENGTH = 9
-	SIRET_LENGTH = 14
-)
-
-func (s *companyService) SearchByIdentifier(ctx context.Context, identifier string) (*models.CompanySearchResult, error) {
-	switch len(identifier) {
-	case SIRET_LENGTH:
-		return s.lookupBySiret(ctx, identifier)
-	case SIREN_LENGTH:
-		return s.lookupBySiren(ctx, identifier)
+// SearchByIdentifier looks up a SIREN or SIRET already normalized by
+// identifier.Normalize.
+func (s *companyService) SearchByIdentifier(ctx context.Context, number string) (*models.CompanySearchResult, error) {
+	switch len(number) {
+	case identifier.SIRET_LENGTH:
+		return s.lookupBySiret(ctx, number)
+	case identifier.SIREN_LENGTH:
+		return s.lookupBySiren(ctx, number)
 	default:
 		return nil, fmt.Errorf("identifier must be a 9-digit SIREN or 14-digit SIRET")
 	}
@@ -28,7 +26,7 @@ func (s *companyService) lookupBySiren(ctx context.Context, siren string) (*mode
 		FROM etablissement e
 		JOIN unite_legale u ON e.siren = u.siren
 		LEFT JOIN naf_reference naf ON COALESCE(NULLIF(e.activite_principale_etablissement, ''), u.activite_principale_unite_legale, '') = naf.code
-		WHERE e.etablissement_siege = 'true' AND u.siren = $1
+		WHERE e.etablissement_siege AND u.siren = $1
 		LIMIT 1`, companySelectFields)
 
 	c, err := scanCompanyRow(s.db.QueryRowContext(ctx, query, siren))
@@ -6,8 +6,8 @@ import (
 	"sirene-importer/api/models"
 )
 
-func (s *companyService) SearchMultiCriteria(ctx context.Context, criteria models.CompanySearchCriteria, limit int, offset int) (*models.CompanySearchResult, error) {
-	conditions := []string{"e.etablissement_siege = 'true'"}
+func (s *companyService) SearchMultiCriteria(ctx context.Context, criteria models.CompanySearchCriteria, page models.PageRequest) (*models.CompanySearchResult, error) {
+	conditions := []string{"e.etablissement_siege"}
 	var args []any
 	argN := 1
 
@@ -30,6 +30,7 @@ func (s *companyService) SearchMultiCriteria(ctx context.Context, criteria model
 	}
 
 	if criteria.Denomination != "" {
+		conditions = append(conditions, denominationSearchable)
 		conditions = append(conditions, fmt.Sprintf("immutable_unaccent(u.denomination_unite_legale) ILIKE immutable_unaccent($%d)", argN))
 		args = append(args, "%"+criteria.Denomination+"%")
 		argN++
@@ -84,11 +85,11 @@ func (s *companyService) SearchMultiCriteria(ctx context.Context, criteria model
 		}, nil
 	}
 
-	cacheKey := fmt.Sprintf("sirene:v2:multi:%s:%s:%s:%s:%s:%s:%s:%s:%s:%s:%s",
+	cacheKey := fmt.Sprintf("sirene:v3:multi:%s:%s:%s:%s:%s:%s:%s:%s:%s:%s:%s",
 		criteria.Siren, criteria.Siret,
 		criteria.NafCode, criteria.Denomination, criteria.CodePostal, criteria.Commune,
 		criteria.EtatAdministratif, criteria.DateCreationFrom, criteria.DateCreationTo,
 		criteria.CategorieJuridique, criteria.TrancheEffectifs)
 
-	return s.searchCompanies(ctx, conditions, args, limit, offset, cacheKey, criteria)
+	return s.searchCompanies(ctx, conditions, args, page, cacheKey, criteria)
 }
@@ -0,0 +1,117 @@
+package company
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sirene-importer/api/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+const MAX_NAME_SEARCH_LIMIT = 100
+
+// LEGAL_FORMS are dropped from the query and from the denominations before
+// they are compared, so "SOCIETE GENERALE SA" scores like "Societe Generale".
+var LEGAL_FORMS = []string{
+	"SA", "SAS", "SASU", "SARL", "EURL", "SNC", "SCS", "SCA", "SCI", "SCOP", "SCP",
+	"SELARL", "SELAS", "SEM", "GIE", "SE",
+}
+
+var legalFormPattern = `\m(` + strings.Join(LEGAL_FORMS, "|") + `)\M`
+
+// stripLegalForms uppercases a name, drops the dots of "S.A.R.L." and
+// removes the legal form words. A name made only of legal forms is kept.
+func stripLegalForms(name string) string {
+	name = strings.ToUpper(strings.ReplaceAll(name, ".", ""))
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return strings.ContainsRune(" ,;:()'\"-&/", r)
+	})
+
+	kept := make([]string, 0, len(words))
+	for _, word := range words {
+		if !slices.Contains(LEGAL_FORMS, word) {
+			kept = append(kept, word)
+		}
+	}
+	if len(kept) == 0 {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(kept, " ")
+}
+
+// scoredRow appends the score columns to the fields read by scanCompanyRow.
+type scoredRow struct {
+	rows  *sql.Rows
+	extra []any
+}
+
+func (r scoredRow) Scan(dest ...any) error {
+	return r.rows.Scan(append(dest, r.extra...)...)
+}
+
+// SearchByName ranks unite legales by how close their denomination is to
+// name, with the unaccent trigram index. The score averages word_similarity,
+// which does not penalize extra words in the denomination, and similarity
+// once legal forms are stripped on both sides, which favors exact names.
+func (s *companyService) SearchByName(ctx context.Context, name string, limit, offset int) (*models.CompanySearchResult, error) {
+	criteria := models.CompanySearchCriteria{Denomination: name}
+	query := stripLegalForms(name)
+	if query == "" {
+		return &models.CompanySearchResult{Criteria: criteria, Results: []models.CompanyResult{}}, nil
+	}
+	limit = min(limit, MAX_NAME_SEARCH_LIMIT)
+
+	cacheKey := fmt.Sprintf("sirene:v3:name:%s:l%d:o%d", query, limit, offset)
+	var cached models.CompanySearchResult
+	if err := s.cache.Get(cacheKey, &cached); err == nil {
+		return &cached, nil
+	}
+
+	denomination := "immutable_unaccent(u.denomination_unite_legale)"
+	stripped := fmt.Sprintf("regexp_replace(replace(upper(%s), '.', ''), '%s', ' ', 'g')", denomination, legalFormPattern)
+
+	dataQuery := fmt.Sprintf(`SELECT %s, r.score
+		FROM (
+			SELECT u.siren,
+				round(((word_similarity(immutable_unaccent($1), %s) + similarity(immutable_unaccent($1), %s)) / 2)::numeric, 3)::float8 AS score
+			FROM unite_legale u
+			WHERE immutable_unaccent($1) <%% %s AND %s
+			ORDER BY score DESC, u.siren
+			LIMIT $2 OFFSET $3
+		) r
+		JOIN unite_legale u ON u.siren = r.siren
+		JOIN etablissement e ON e.siren = u.siren AND e.etablissement_siege
+		LEFT JOIN naf_reference naf ON COALESCE(NULLIF(e.activite_principale_etablissement, ''), u.activite_principale_unite_legale, '') = naf.code
+		ORDER BY r.score DESC, u.siren`,
+		companySelectFields, denomination, stripped, denomination, denominationSearchable)
+
+	rows, err := s.db.QueryContext(ctx, dataQuery, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("name search failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	companies := make([]models.CompanyResult, 0, limit)
+	for rows.Next() {
+		var score float64
+		c, err := scanCompanyRow(scoredRow{rows: rows, extra: []any{&score}})
+		if err != nil {
+			return nil, fmt.Errorf("name search scan failed: %w", err)
+		}
+		c.Score = score
+		companies = append(companies, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	result := &models.CompanySearchResult{
+		Criteria: criteria,
+		Results:  companies,
+		Meta:     models.Meta{Count: len(companies), Limit: limit, Offset: offset},
+	}
+	_ = s.cache.Set(cacheKey, result, 1*time.Hour)
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package company
+
+import (
+	"context"
+	"fmt"
+	"sirene-importer/api/models"
+	"strings"
+	"time"
+)
+
+const (
+	SUGGEST_LIMIT      = 10
+	MIN_SUGGEST_LENGTH = 2
+
+	// suggestKey is the expression of idx_ul_suggest. The "C" collation
+	// keeps the btree in byte order, so every name starting with a prefix
+	// sits in one range, read in index order up to the limit.
+	suggestKey = `(immutable_unaccent(upper(u.denomination_unite_legale)) COLLATE "C")`
+)
+
+// Suggest returns the first active, public unite legales whose denomination
+// starts with prefix, accents and case ignored, with the commune of their
+// siege. Results are cached per prefix.
+func (s *companyService) Suggest(ctx context.Context, prefix string) ([]models.Suggestion, error) {
+	prefix = strings.ToUpper(strings.Join(strings.Fields(prefix), " "))
+
+	cacheKey := fmt.Sprintf("sirene:v3:suggest:%s", prefix)
+	var cached []models.Suggestion
+	if err := s.cache.Get(cacheKey, &cached); err == nil {
+		return cached, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT u.siren, u.denomination_unite_legale, COALESCE(e.siret, ''),
+			COALESCE(e.code_postal_etablissement, ''), COALESCE(e.libelle_commune_etablissement, '')
+		FROM (
+			SELECT u.siren, u.denomination_unite_legale, %[1]s AS key
+			FROM unite_legale u
+			WHERE %[1]s >= immutable_unaccent($1)
+				AND %[1]s < immutable_unaccent($1) || chr(1114111)
+				AND u.etat_administratif_unite_legale = 'A'
+				AND %[2]s
+			ORDER BY %[1]s, u.siren
+			LIMIT $2
+		) u
+		LEFT JOIN etablissement e ON e.siren = u.siren AND e.etablissement_siege
+		ORDER BY u.key, u.siren`, suggestKey, denominationSearchable), prefix, SUGGEST_LIMIT)
+	if err != nil {
+		return nil, fmt.Errorf("suggest failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	suggestions := make([]models.Suggestion, 0, SUGGEST_LIMIT)
+	for rows.Next() {
+		var sg models.Suggestion
+		if err := rows.Scan(&sg.Siren, &sg.Denomination, &sg.Siret, &sg.CodePostal, &sg.LibelleCommune); err != nil {
+			return nil, fmt.Errorf("suggest scan failed: %w", err)
+		}
+		suggestions = append(suggestions, sg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	_ = s.cache.Set(cacheKey, suggestions, 1*time.Hour)
+	return suggestions, nil
+}
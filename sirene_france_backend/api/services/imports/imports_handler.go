@@ -0,0 +1,37 @@
+package imports
+
+import (
+	"net/http"
+	"sirene-importer/api/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *importService
+}
+
+func NewHandler(service *importService) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) ListImports(c *gin.Context) {
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, models.Error("limit must be a positive integer"))
+			return
+		}
+		limit = min(parsed, 100)
+	}
+
+	runs, err := h.service.ListRuns(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessWithMeta(runs, models.Meta{Count: len(runs), Limit: limit}))
+}
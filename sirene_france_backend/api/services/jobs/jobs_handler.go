@@ -0,0 +1,115 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sirene-importer/api/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service *jobService
+}
+
+func NewHandler(service *jobService) *Handler {
+	return &Handler{service: service}
+}
+
+func parseJobID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, models.Error("id must be a positive integer"))
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *Handler) SubmitEnrichJob(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error("file field required (multipart CSV upload)"))
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	if header.Size > MAX_JOB_FILE_SIZE {
+		c.JSON(http.StatusRequestEntityTooLarge, models.Error(fmt.Sprintf("file larger than %d MB", MAX_JOB_FILE_SIZE>>20)))
+		return
+	}
+	input, err := io.ReadAll(io.LimitReader(file, MAX_JOB_FILE_SIZE+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error("read file: "+err.Error()))
+		return
+	}
+	if len(input) > MAX_JOB_FILE_SIZE {
+		c.JSON(http.StatusRequestEntityTooLarge, models.Error(fmt.Sprintf("file larger than %d MB", MAX_JOB_FILE_SIZE>>20)))
+		return
+	}
+
+	columns := JobColumns{
+		Identifier: c.PostForm("identifier_column"),
+		Name:       c.PostForm("name_column"),
+		PostalCode: c.PostForm("postal_code_column"),
+	}
+	job, err := h.service.Submit(c.Request.Context(), filepath.Base(header.Filename), input, columns)
+	if errors.Is(err, ErrInvalidJobFile) {
+		c.JSON(http.StatusBadRequest, models.Error(err.Error()))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.Success(job))
+}
+
+func (h *Handler) GetJob(c *gin.Context) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.service.GetJob(c.Request.Context(), id)
+	if errors.Is(err, ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, models.Error(err.Error()))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(job))
+}
+
+func (h *Handler) GetJobResult(c *gin.Context) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	job, result, err := h.service.GetResult(c.Request.Context(), id)
+	if errors.Is(err, ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, models.Error(err.Error()))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(err.Error()))
+		return
+	}
+	if job.Status != JOB_COMPLETED {
+		c.JSON(http.StatusConflict, models.Error("job is "+job.Status+", no result yet"))
+		return
+	}
+
+	name := strings.TrimSuffix(job.FileName, filepath.Ext(job.FileName)) + "_enriched.csv"
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", result)
+}
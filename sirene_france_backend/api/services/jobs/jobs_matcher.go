@@ -0,0 +1,248 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sirene-importer/api/models"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	MATCH_FOUND     = "matched"
+	MATCH_NOT_FOUND = "not_found"
+	MATCH_INVALID   = "invalid_identifier"
+
+	MATCH_BY_IDENTIFIER = "identifier"
+	MATCH_BY_NAME       = "name_postal_code"
+)
+
+// Header names recognized when the job does not name its columns.
+var (
+	identifierHeaders = []string{"siret", "siren", "identifiant", "identifier"}
+	nameHeaders       = []string{"denomination", "raison_sociale", "nom", "name", "entreprise", "societe", "company"}
+	postalCodeHeaders = []string{"code_postal", "cp", "codepostal", "postal_code", "zipcode", "zip"}
+)
+
+// enrichColumns are appended to every row of the result.
+var enrichColumns = []string{
+	"match_status", "match_method", "match_confidence",
+	"sirene_siren", "sirene_siret", "sirene_denomination", "sirene_categorie_juridique",
+	"sirene_naf_code", "sirene_naf_label", "sirene_etat_administratif", "sirene_date_creation",
+	"sirene_tranche_effectifs", "sirene_adresse", "sirene_code_postal", "sirene_libelle_commune",
+}
+
+type matchResult struct {
+	status     string
+	method     string
+	confidence float64
+	company    *models.CompanyResult
+}
+
+func (m matchResult) fields() []string {
+	fields := make([]string, len(enrichColumns))
+	fields[0] = m.status
+	if m.company == nil {
+		return fields
+	}
+
+	c := m.company
+	address := strings.Join(strings.Fields(c.NumeroVoie+" "+c.TypeVoie+" "+c.LibelleVoie), " ")
+	copy(fields[1:], []string{
+		m.method, strconv.FormatFloat(m.confidence, 'f', 2, 64),
+		c.Siren, c.Siret, c.Denomination, c.CategorieJuridique,
+		c.NafCode, c.NafLabel, c.EtatAdministratif, c.DateCreation,
+		c.TrancheEffectifs, address, c.CodePostal, c.LibelleCommune,
+	})
+	return fields
+}
+
+// resolveColumns checks the requested columns against the header, or guesses
+// them. A job needs an identifier column or a name and a postal code column.
+func resolveColumns(header []string, columns JobColumns) (JobColumns, error) {
+	cleaned := make([]string, len(header))
+	for i, h := range header {
+		cleaned[i] = cleanHeader(h)
+	}
+
+	resolve := func(requested string, candidates []string) (string, error) {
+		if requested != "" {
+			if !slices.Contains(cleaned, cleanHeader(requested)) {
+				return "", fmt.Errorf("%w: column %q not found in header", ErrInvalidJobFile, requested)
+			}
+			return cleanHeader(requested), nil
+		}
+		for _, candidate := range candidates {
+			if slices.Contains(cleaned, candidate) {
+				return candidate, nil
+			}
+		}
+		return "", nil
+	}
+
+	var err error
+	if columns.Identifier, err = resolve(columns.Identifier, identifierHeaders); err != nil {
+		return columns, err
+	}
+	if columns.Name, err = resolve(columns.Name, nameHeaders); err != nil {
+		return columns, err
+	}
+	if columns.PostalCode, err = resolve(columns.PostalCode, postalCodeHeaders); err != nil {
+		return columns, err
+	}
+
+	if columns.Identifier == "" && (columns.Name == "" || columns.PostalCode == "") {
+		return columns, fmt.Errorf("%w: needs a SIREN/SIRET column or a name and a postal code column", ErrInvalidJobFile)
+	}
+	return columns, nil
+}
+
+type columnIndex struct {
+	identifier, name, postalCode int
+}
+
+func columnIndexes(header []string, columns JobColumns) columnIndex {
+	index := columnIndex{identifier: -1, name: -1, postalCode: -1}
+	for i, h := range header {
+		switch h = cleanHeader(h); {
+		case columns.Identifier != "" && h == columns.Identifier && index.identifier < 0:
+			index.identifier = i
+		case columns.Name != "" && h == columns.Name && index.name < 0:
+			index.name = i
+		case columns.PostalCode != "" && h == columns.PostalCode && index.postalCode < 0:
+			index.postalCode = i
+		}
+	}
+	return index
+}
+
+func field(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// matchChunk matches rows by SIREN/SIRET first, then the rows left by name
+// and postal code, with a fixed number of queries per chunk.
+func (s *jobService) matchChunk(ctx context.Context, rows [][]string, index columnIndex) ([]matchResult, error) {
+	matches := make([]matchResult, len(rows))
+	for i := range matches {
+		matches[i].status = MATCH_NOT_FOUND
+	}
+
+	var identifiers []string
+	var identifierRows []int
+	for i, row := range rows {
+		if value := field(row, index.identifier); value != "" {
+			identifiers = append(identifiers, value)
+			identifierRows = append(identifierRows, i)
+		}
+	}
+
+	if len(identifiers) > 0 {
+		results, err := s.lookup.LookupIdentifiers(ctx, identifiers)
+		if err != nil {
+			return nil, err
+		}
+		for j, result := range results {
+			m := &matches[identifierRows[j]]
+			switch result.Status {
+			case models.LOOKUP_FOUND:
+				*m = matchResult{status: MATCH_FOUND, method: MATCH_BY_IDENTIFIER, confidence: 1, company: result.Company}
+			case models.LOOKUP_INVALID:
+				m.status = MATCH_INVALID
+			}
+		}
+	}
+
+	var names, postalCodes []string
+	var nameRows []int
+	for i, row := range rows {
+		if matches[i].status == MATCH_FOUND {
+			continue
+		}
+		name, postalCode := field(row, index.name), field(row, index.postalCode)
+		if name != "" && postalCode != "" {
+			names = append(names, name)
+			postalCodes = append(postalCodes, postalCode)
+			nameRows = append(nameRows, i)
+		}
+	}
+	if len(names) == 0 {
+		return matches, nil
+	}
+
+	candidates, err := s.matchNames(ctx, names, postalCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	var sirets []string
+	var siretRows []int
+	for j, candidate := range candidates {
+		if candidate.siret != "" {
+			sirets = append(sirets, candidate.siret)
+			siretRows = append(siretRows, j)
+		}
+	}
+	if len(sirets) == 0 {
+		return matches, nil
+	}
+
+	results, err := s.lookup.LookupIdentifiers(ctx, sirets)
+	if err != nil {
+		return nil, err
+	}
+	for k, result := range results {
+		if result.Status != models.LOOKUP_FOUND {
+			continue
+		}
+		j := siretRows[k]
+		matches[nameRows[j]] = matchResult{
+			status: MATCH_FOUND, method: MATCH_BY_NAME, confidence: candidates[j].score, company: result.Company,
+		}
+	}
+	return matches, nil
+}
+
+type nameCandidate struct {
+	siret string
+	score float64
+}
+
+// matchNames finds, for each name and postal code pair, the establishment in
+// that postal code whose unite legale denomination is the closest trigram
+// match. Denominations withheld from diffusion are not matched.
+func (s *jobService) matchNames(ctx context.Context, names, postalCodes []string) ([]nameCandidate, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT q.n, m.siret, m.score
+		FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS q(name, code_postal, n)
+		CROSS JOIN LATERAL (
+			SELECT e.siret,
+				similarity(immutable_unaccent(u.denomination_unite_legale), immutable_unaccent(q.name)) AS score
+			FROM etablissement e
+			JOIN unite_legale u ON u.siren = e.siren
+			WHERE e.code_postal_etablissement = q.code_postal
+				AND COALESCE(u.statut_diffusion_unite_legale, 'O') = 'O'
+				AND immutable_unaccent(u.denomination_unite_legale) % immutable_unaccent(q.name)
+			ORDER BY score DESC, e.etablissement_siege DESC, e.etat_administratif_etablissement = 'A' DESC
+			LIMIT 1
+		) m`, names, postalCodes)
+	if err != nil {
+		return nil, fmt.Errorf("name match failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	candidates := make([]nameCandidate, len(names))
+	for rows.Next() {
+		var n int
+		var candidate nameCandidate
+		if err := rows.Scan(&n, &candidate.siret, &candidate.score); err != nil {
+			return nil, fmt.Errorf("name match scan failed: %w", err)
+		}
+		candidates[n-1] = candidate
+	}
+	return candidates, rows.Err()
+}
@@ -0,0 +1,318 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sirene-importer/api/models"
+	"strings"
+	"time"
+)
+
+const (
+	JOB_PENDING   = "pending"
+	JOB_RUNNING   = "running"
+	JOB_COMPLETED = "completed"
+	JOB_FAILED    = "failed"
+
+	MAX_JOB_ROWS      = 100000
+	MAX_JOB_FILE_SIZE = 20 << 20
+
+	JOB_CHUNK_SIZE    = 500
+	JOB_POLL_INTERVAL = 5 * time.Second
+
+	// A running job whose progress has not moved for this long belongs to an
+	// API that stopped, and is picked up again.
+	JOB_STALE_AFTER = 10 * time.Minute
+)
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrInvalidJobFile = errors.New("invalid file")
+)
+
+type Job struct {
+	ID               int64      `json:"id"`
+	FileName         string     `json:"file_name"`
+	Status           string     `json:"status"`
+	IdentifierColumn string     `json:"identifier_column,omitempty"`
+	NameColumn       string     `json:"name_column,omitempty"`
+	PostalCodeColumn string     `json:"postal_code_column,omitempty"`
+	Rows             int        `json:"rows"`
+	Processed        int        `json:"processed"`
+	Matched          int        `json:"matched"`
+	Error            string     `json:"error,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	FinishedAt       *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobColumns names the columns of the uploaded file used for matching. Empty
+// names are guessed from the header.
+type JobColumns struct {
+	Identifier string
+	Name       string
+	PostalCode string
+}
+
+type lookupService interface {
+	LookupIdentifiers(ctx context.Context, inputs []string) ([]models.LookupResult, error)
+}
+
+type jobService struct {
+	db     *sql.DB
+	lookup lookupService
+	wake   chan struct{}
+}
+
+func NewJobService(db *sql.DB, lookup lookupService) *jobService {
+	s := &jobService{db: db, lookup: lookup, wake: make(chan struct{}, 1)}
+	if err := s.setup(); err != nil {
+		slog.Error("Enrich jobs table setup failed", "error", err)
+	}
+	return s
+}
+
+// setup creates enrich_jobs. The uploaded file and the result are kept in
+// the table, so jobs survive a restart of the API.
+func (s *jobService) setup() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS enrich_jobs (
+		id BIGSERIAL PRIMARY KEY,
+		file_name TEXT NOT NULL,
+		status TEXT NOT NULL,
+		identifier_column TEXT NOT NULL DEFAULT '',
+		name_column TEXT NOT NULL DEFAULT '',
+		postal_code_column TEXT NOT NULL DEFAULT '',
+		rows INTEGER NOT NULL DEFAULT 0,
+		processed INTEGER NOT NULL DEFAULT 0,
+		matched INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		input BYTEA NOT NULL,
+		result BYTEA,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		started_at TIMESTAMPTZ,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		finished_at TIMESTAMPTZ
+	)`)
+	return err
+}
+
+// Submit checks the header of an uploaded CSV, resolves its matching columns
+// and queues it.
+func (s *jobService) Submit(ctx context.Context, fileName string, input []byte, columns JobColumns) (*Job, error) {
+	records, _, err := readCSV(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("%w: expected a header and at least one row", ErrInvalidJobFile)
+	}
+	if len(records)-1 > MAX_JOB_ROWS {
+		return nil, fmt.Errorf("%w: %d rows, at most %d per job", ErrInvalidJobFile, len(records)-1, MAX_JOB_ROWS)
+	}
+
+	columns, err = resolveColumns(records[0], columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, `INSERT INTO enrich_jobs
+		(file_name, status, identifier_column, name_column, postal_code_column, rows, input)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		fileName, JOB_PENDING, columns.Identifier, columns.Name, columns.PostalCode, len(records)-1, input).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	slog.Info("Enrich job queued", "id", id, "file", fileName, "rows", len(records)-1)
+	return s.GetJob(ctx, id)
+}
+
+func (s *jobService) GetJob(ctx context.Context, id int64) (*Job, error) {
+	var j Job
+	err := s.db.QueryRowContext(ctx, `SELECT id, file_name, status, identifier_column, name_column, postal_code_column,
+			rows, processed, matched, error, created_at, started_at, finished_at
+		FROM enrich_jobs WHERE id = $1`, id).Scan(&j.ID, &j.FileName, &j.Status, &j.IdentifierColumn, &j.NameColumn,
+		&j.PostalCodeColumn, &j.Rows, &j.Processed, &j.Matched, &j.Error, &j.CreatedAt, &j.StartedAt, &j.FinishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get job: %w", err)
+	}
+	return &j, nil
+}
+
+// GetResult returns the enriched CSV of a job, nil while it is not completed.
+func (s *jobService) GetResult(ctx context.Context, id int64) (*Job, []byte, error) {
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != JOB_COMPLETED {
+		return job, nil, nil
+	}
+
+	var result []byte
+	if err := s.db.QueryRowContext(ctx, "SELECT result FROM enrich_jobs WHERE id = $1", id).Scan(&result); err != nil {
+		return nil, nil, fmt.Errorf("get job result: %w", err)
+	}
+	return job, result, nil
+}
+
+// Run processes the queued jobs one at a time until ctx is done. Jobs left
+// running by a stopped API are taken over once stale.
+func (s *jobService) Run(ctx context.Context) {
+	ticker := time.NewTicker(JOB_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		for s.runNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *jobService) runNext(ctx context.Context) bool {
+	var id int64
+	var columns JobColumns
+	var input []byte
+	err := s.db.QueryRowContext(ctx, `UPDATE enrich_jobs
+		SET status = $1, started_at = now(), updated_at = now(), processed = 0, matched = 0
+		WHERE id = (
+			SELECT id FROM enrich_jobs
+			WHERE status = $2 OR (status = $1 AND updated_at < now() - make_interval(secs => $3))
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, identifier_column, name_column, postal_code_column, input`,
+		JOB_RUNNING, JOB_PENDING, JOB_STALE_AFTER.Seconds()).Scan(&id, &columns.Identifier, &columns.Name, &columns.PostalCode, &input)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		slog.Error("Claim enrich job failed", "error", err)
+		return false
+	}
+
+	start := time.Now()
+	slog.Info("Enrich job started", "id", id)
+
+	result, matched, err := s.process(ctx, id, input, columns)
+	if err != nil {
+		slog.Error("Enrich job failed", "id", id, "error", err)
+		_, _ = s.db.ExecContext(context.Background(), `UPDATE enrich_jobs
+			SET status = $1, error = $2, updated_at = now(), finished_at = now() WHERE id = $3`, JOB_FAILED, err.Error(), id)
+		return true
+	}
+
+	if _, err := s.db.ExecContext(ctx, `UPDATE enrich_jobs
+		SET status = $1, matched = $2, result = $3, updated_at = now(), finished_at = now() WHERE id = $4`,
+		JOB_COMPLETED, matched, result, id); err != nil {
+		slog.Error("Save enrich job result failed", "id", id, "error", err)
+		return true
+	}
+
+	slog.Info("Enrich job completed", "id", id, "matched", matched, "duration", time.Since(start))
+	return true
+}
+
+// process enriches the rows chunk by chunk and returns the output CSV, with
+// the registry fields appended to the original columns.
+func (s *jobService) process(ctx context.Context, id int64, input []byte, columns JobColumns) ([]byte, int, error) {
+	records, delimiter, err := readCSV(input)
+	if err != nil {
+		return nil, 0, err
+	}
+	header, rows := records[0], records[1:]
+	index := columnIndexes(header, columns)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = delimiter
+	if err := w.Write(append(header[:len(header):len(header)], enrichColumns...)); err != nil {
+		return nil, 0, err
+	}
+
+	matched := 0
+	for start := 0; start < len(rows); start += JOB_CHUNK_SIZE {
+		chunk := rows[start:min(start+JOB_CHUNK_SIZE, len(rows))]
+
+		matches, err := s.matchChunk(ctx, chunk, index)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i, row := range chunk {
+			if matches[i].status == MATCH_FOUND {
+				matched++
+			}
+			if err := w.Write(append(row[:len(row):len(row)], matches[i].fields()...)); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		if _, err := s.db.ExecContext(ctx, "UPDATE enrich_jobs SET processed = $1, matched = $2, updated_at = now() WHERE id = $3",
+			start+len(chunk), matched, id); err != nil {
+			return nil, 0, fmt.Errorf("update progress: %w", err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), matched, nil
+}
+
+// readCSV parses an upload separated by semicolons or commas, whichever the
+// header line uses most.
+func readCSV(input []byte) ([][]string, rune, error) {
+	input = bytes.TrimPrefix(input, []byte("\xef\xbb\xbf"))
+	firstLine, _, _ := bytes.Cut(input, []byte("\n"))
+	delimiter := ','
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		delimiter = ';'
+	}
+
+	r := csv.NewReader(bytes.NewReader(input))
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidJobFile, err)
+		}
+		records = append(records, record)
+	}
+	return records, delimiter, nil
+}
+
+func cleanHeader(header string) string {
+	header = strings.ToLower(strings.TrimSpace(header))
+	header = strings.ReplaceAll(header, " ", "_")
+	return strings.ReplaceAll(header, "-", "_")
+}
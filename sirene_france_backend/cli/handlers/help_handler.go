@@ -10,14 +10,24 @@ Usage: sirene-api <commande>
 
 Commandes:
   api                    Démarrer le serveur API (port 8081)
-  all                    Importer tous les fichiers ZIP depuis ../sirene_data
-  indexes                Créer les indexes PostgreSQL (btree + trigram)
+  all [--resume]         Importer tous les fichiers ZIP depuis ../sirene_data
+                         (--resume reprend le dernier import interrompu)
+  update [dossier]       Appliquer les fichiers de mise à jour (CSV/ZIP) depuis ../sirene_data/updates
+  rollback               Restaurer la génération précédente des tables (échange avec *__previous)
+  indexes [--concurrently] [--workers n]
+                         Créer les indexes PostgreSQL (btree + trigram), reconstruit les indexes invalides
+                         (--concurrently sans bloquer les écritures, --workers défaut INDEX_WORKERS=4)
+  indexes status         État des indexes (valide, invalide, absent) et créations en cours
   naf                    Importer les codes NAF depuis data/naf_codes.json
+  imports                Historique des imports (fichier, checksum, date d'extrait, lignes, rejets, durée)
   tables                 Lister les tables de la base de données
   help                   Afficher cette aide
 
 Endpoints API (port 8081):
   GET /api/health
+  GET /api/imports?limit={n}
+  GET /api/admin/indexes
+  GET /api/companies/{siren}?etat={A|F}&limit={n}&offset={n}
   GET /api/companies/search/naf?code={code}&limit={n}&offset={n}
   GET /api/companies/search/denomination?q={query}&limit={n}&offset={n}
   GET /api/companies/search/codepostal?q={code}&limit={n}&offset={n}
@@ -29,6 +39,7 @@ Endpoints API (port 8081):
 Paramètres de pagination:
   limit                  Nombre de résultats par page (défaut: 100, max: 10000)
   offset                 Position de départ dans les résultats (défaut: 0)
+  after                  Curseur <date_creation>,<siren> (meta.next_cursor), remplace offset
 
 Exemples:
   curl "localhost:8081/api/companies/search/naf?code=62.01Z&limit=10"
@@ -3,12 +3,38 @@ package handlers
 import (
 	"database/sql"
 	"fmt"
+	"os"
 	"sirene-importer/csv"
+	"slices"
 )
 
-func HandleImportAll(db *sql.DB) {
+func HandleImportAll(db *sql.DB, args []string) {
+	resume := slices.Contains(args, "--resume")
+
 	fmt.Println("Importing SIRENE ZIP files...")
-	if err := csv.ProcessAllZIPs(db, "../sirene_data"); err != nil {
+	if err := csv.ProcessAllZIPs(db, "../sirene_data", resume); err != nil {
 		fmt.Printf("Import error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func HandleUpdate(db *sql.DB, args []string) {
+	updateDir := "../sirene_data/updates"
+	if len(args) > 0 {
+		updateDir = args[0]
+	}
+
+	fmt.Printf("Applying SIRENE update files from %s...\n", updateDir)
+	if err := csv.ProcessAllUpdates(db, updateDir); err != nil {
+		fmt.Printf("Update error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func HandleRollback(db *sql.DB) {
+	fmt.Println("Restauration de la generation precedente...")
+	if err := csv.RollbackTables(db); err != nil {
+		fmt.Printf("Rollback error: %v\n", err)
+		os.Exit(1)
 	}
 }
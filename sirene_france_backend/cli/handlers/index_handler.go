@@ -1,13 +1,75 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"os"
+	"sirene-importer/config"
 	"sirene-importer/csv"
+	"strconv"
 )
 
-func HandleCreateIndexes(db *sql.DB) {
-	if err := csv.CreateIndexes(db); err != nil {
+func HandleCreateIndexes(db *sql.DB, args []string) {
+	if len(args) > 0 && args[0] == "status" {
+		showIndexStatus(db)
+		return
+	}
+
+	opts := csv.IndexOptions{Workers: config.Load().IndexWorkers}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--concurrently":
+			opts.Concurrently = true
+		case "--workers":
+			if i+1 >= len(args) {
+				fmt.Println("Erreur: --workers attend un nombre")
+				os.Exit(1)
+			}
+			workers, err := strconv.Atoi(args[i+1])
+			if err != nil || workers <= 0 {
+				fmt.Printf("Erreur: nombre de workers invalide %q\n", args[i+1])
+				os.Exit(1)
+			}
+			opts.Workers = workers
+			i++
+		default:
+			fmt.Printf("Erreur: option inconnue %q\n", args[i])
+			os.Exit(1)
+		}
+	}
+
+	if err := csv.CreateIndexes(db, opts); err != nil {
+		fmt.Printf("Erreur: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func showIndexStatus(db *sql.DB) {
+	ctx := context.Background()
+	infos, err := csv.IndexStatus(ctx, db)
+	if err != nil {
 		fmt.Printf("Erreur: %v\n", err)
+		return
+	}
+
+	for _, info := range infos {
+		fmt.Printf("   %-32s %-14s %-8s %8.1f MB\n", info.Name, info.Table, info.Status, float64(info.SizeBytes)/(1<<20))
+	}
+
+	builds, err := csv.IndexProgress(ctx, db)
+	if err != nil {
+		fmt.Printf("Erreur: %v\n", err)
+		return
+	}
+	if len(builds) == 0 {
+		fmt.Println("\nAucune creation d'index en cours")
+		return
+	}
+
+	fmt.Println("\nCreations en cours:")
+	for _, b := range builds {
+		fmt.Printf("   pid %-7d %-14s %-32s %s, %s %.1f%% (%ds)\n",
+			b.PID, b.Table, b.Index, b.Command, b.Phase, b.Percent, b.ElapsedSec)
 	}
 }
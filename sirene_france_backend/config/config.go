@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +13,10 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBName     string
+
+	ImportMaxRejects int
+	RejectsDir       string
+	IndexWorkers     int
 }
 
 func Load() *Config {
@@ -23,6 +28,10 @@ func Load() *Config {
 		DBUser:     getEnv("POSTGRES_USER", ""),
 		DBPassword: getEnv("POSTGRES_PASSWORD", ""),
 		DBName:     getEnv("POSTGRES_DB", "sirene_db"),
+
+		ImportMaxRejects: getEnvInt("IMPORT_MAX_REJECTS", 1000),
+		RejectsDir:       getEnv("IMPORT_REJECTS_DIR", "rejects"),
+		IndexWorkers:     getEnvInt("INDEX_WORKERS", 4),
 	}
 }
 
@@ -32,3 +41,10 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -2,36 +2,106 @@ package csv
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sirene-importer/config"
-	"sirene-importer/database"
+	"io"
+	"net"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func InsertBatch(tableName string, headers []string, batch [][]string) error {
-	if len(batch) == 0 {
-		return nil
-	}
+const (
+	COPY_MAX_ATTEMPTS = 3
+	COPY_RETRY_DELAY  = 2 * time.Second
+)
 
-	cfg := config.Load()
-	conn, err := database.ConnectPgxNative(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to connect for batch insert: %w", err)
+type rowCopier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// copyWorker keeps one pooled connection for the whole life of a pipeline
+// worker and only goes back to the pool when that connection breaks.
+type copyWorker struct {
+	pool *pgxpool.Pool
+	conn *pgxpool.Conn
+}
+
+func (w *copyWorker) release() {
+	if w.conn != nil {
+		w.conn.Release()
+		w.conn = nil
 	}
-	defer func() { _ = conn.Close(context.Background()) }()
+}
+
+// insertRecordBatch copies a batch, retrying on a fresh connection when the
+// failure is transient. A failed COPY leaves no rows behind, and with a
+// checkpoint the batch and its import_batches row share one transaction.
+func (w *copyWorker) insertRecordBatch(tableName string, headers []string, batch recordBatch, rows [][]any, cp *fileCheckpoint) error {
+	ctx := context.Background()
+
+	var err error
+	for attempt := 1; attempt <= COPY_MAX_ATTEMPTS; attempt++ {
+		if attempt > 1 {
+			fmt.Printf("Lot %d: nouvelle tentative %d/%d apres erreur de connexion: %v\n",
+				batch.Index, attempt, COPY_MAX_ATTEMPTS, err)
+			time.Sleep(COPY_RETRY_DELAY * time.Duration(attempt-1))
+		}
+
+		if w.conn == nil {
+			if w.conn, err = w.pool.Acquire(ctx); err != nil {
+				err = fmt.Errorf("failed to acquire connection: %w", err)
+				continue
+			}
+		}
 
-	rows := make([][]any, len(batch))
-	for i, record := range batch {
-		row := make([]any, len(record))
-		for j, v := range record {
-			row[j] = v
+		if cp == nil {
+			err = copyRows(ctx, w.conn, tableName, headers, rows)
+		} else {
+			err = cp.commitBatch(ctx, w.conn.Conn(), tableName, headers, batch, rows)
 		}
-		rows[i] = row
+
+		if err == nil || !isTransientError(err) {
+			return err
+		}
+
+		// Released connections that are closed or mid-transaction are
+		// destroyed by the pool instead of being handed out again.
+		w.release()
+	}
+
+	return err
+}
+
+func isTransientError(err error) bool {
+	if pgconn.SafeToRetry(err) || pgconn.Timeout(err) {
+		return true
 	}
 
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 08: connection exception, 40001/40P01: serialization failure and
+		// deadlock, 57P01-57P03: server shutting down or not yet available.
+		return strings.HasPrefix(pgErr.Code, "08") ||
+			pgErr.Code == "40001" || pgErr.Code == "40P01" ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func copyRows(ctx context.Context, conn rowCopier, tableName string, headers []string, rows [][]any) error {
 	rowsAffected, err := conn.CopyFrom(
-		context.Background(),
+		ctx,
 		pgx.Identifier{tableName},
 		headers,
 		pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
@@ -43,8 +113,8 @@ func InsertBatch(tableName string, headers []string, batch [][]string) error {
 		return fmt.Errorf("copy from failed: %w", err)
 	}
 
-	if rowsAffected != int64(len(batch)) {
-		return fmt.Errorf("expected %d rows, got %d", len(batch), rowsAffected)
+	if rowsAffected != int64(len(rows)) {
+		return fmt.Errorf("expected %d rows, got %d", len(rows), rowsAffected)
 	}
 
 	return nil
@@ -0,0 +1,327 @@
+package csv
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sirene-importer/config"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+type deltaTable struct {
+	Key     string
+	Version string
+}
+
+var deltaTables = map[string]deltaTable{
+	"unite_legale":  {Key: "siren", Version: "date_dernier_traitement_unite_legale"},
+	"etablissement": {Key: "siret", Version: "date_dernier_traitement_etablissement"},
+}
+
+type DeltaResult struct {
+	File     string
+	Table    string
+	Rows     int
+	Inserted int64
+	Updated  int64
+	Skipped  int64
+	Rejects  string
+}
+
+func getUpdateFiles(updateDir string) ([]ZIPFile, error) {
+	var files []ZIPFile
+
+	for _, pattern := range []string{"*.csv", "*.zip"} {
+		entries, err := filepath.Glob(filepath.Join(updateDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			base := filepath.Base(entry)
+			files = append(files, ZIPFile{
+				Name:      base,
+				Path:      entry,
+				TableName: deriveTableName(strings.TrimSuffix(base, ".csv")),
+			})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+func ProcessAllUpdates(db *sql.DB, updateDir string) error {
+	files, err := getUpdateFiles(updateDir)
+	if err != nil {
+		return fmt.Errorf("error scanning update directory: %v", err)
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("no update files found in %s", updateDir)
+	}
+
+	if err := setupUpdateLog(db); err != nil {
+		return err
+	}
+
+	fmt.Printf("Applying %d update files\n", len(files))
+	totalStart := time.Now()
+
+	var results []DeltaResult
+	for i, f := range files {
+		fmt.Printf("\n[%d/%d] Applying %s -> %s...\n", i+1, len(files), f.Name, f.TableName)
+
+		result, err := ApplyUpdateFile(db, f.Path, f.TableName)
+		if err != nil {
+			return fmt.Errorf("error applying %s: %v", f.Name, err)
+		}
+		results = append(results, *result)
+	}
+
+	fmt.Printf("\nUpdates applied in %.2f minutes\n", time.Since(totalStart).Minutes())
+	fmt.Println("\nSUMMARY:")
+	for _, r := range results {
+		fmt.Printf("   %s -> %s: %d rows, %d inserted, %d updated, %d skipped (older than live), %s\n",
+			r.File, r.Table, r.Rows, r.Inserted, r.Updated, r.Skipped, r.Rejects)
+	}
+
+	return nil
+}
+
+func openCSVSource(path string) (io.ReadCloser, error) {
+	if !strings.HasSuffix(strings.ToLower(path), ".zip") {
+		return os.Open(path)
+	}
+
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip %s: %w", path, err)
+	}
+
+	for _, f := range r.File {
+		if !strings.HasSuffix(strings.ToLower(f.Name), ".csv") {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("failed to open CSV in zip: %w", err)
+		}
+		return &zipEntryReader{ReadCloser: rc, archive: r}, nil
+	}
+
+	_ = r.Close()
+	return nil, fmt.Errorf("no CSV file found in %s", path)
+}
+
+type zipEntryReader struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (z *zipEntryReader) Close() error {
+	_ = z.ReadCloser.Close()
+	return z.archive.Close()
+}
+
+func readCSVHeaders(path string) ([]string, error) {
+	rc, err := openCSVSource(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	csvReader := csv.NewReader(rc)
+	csvReader.Comma = ','
+	csvReader.LazyQuotes = true
+
+	headers, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading headers: %w", err)
+	}
+	return headers, nil
+}
+
+func ApplyUpdateFile(db *sql.DB, path, tableName string) (*DeltaResult, error) {
+	spec, ok := deltaTables[tableName]
+	if !ok {
+		return nil, fmt.Errorf("table %s does not support delta updates", tableName)
+	}
+
+	headers, err := readCSVHeaders(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cleanHeaders := make([]string, len(headers))
+	for i, header := range headers {
+		cleanHeaders[i] = CleanColumnName(header)
+	}
+	if !slices.Contains(cleanHeaders, spec.Key) || !slices.Contains(cleanHeaders, spec.Version) {
+		return nil, fmt.Errorf("update file must contain %s and %s columns", spec.Key, spec.Version)
+	}
+
+	stagingTable := tableName + "__delta"
+	if _, err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", stagingTable)); err != nil {
+		return nil, fmt.Errorf("error dropping delta table: %v", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("CREATE UNLOGGED TABLE %s (LIKE %s)", stagingTable, tableName)); err != nil {
+		return nil, fmt.Errorf("error creating delta table: %v", err)
+	}
+	defer func() { _, _ = db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", stagingTable)) }()
+
+	schema, err := liveSchema(db, stagingTable, cleanHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := openCSVSource(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	cfg := config.Load()
+	rejects := NewRejectWriter(cfg.RejectsDir, tableName+"_update")
+	totalLines, err := ProcessPipelineFromReader(rc, stagingTable, schema, rejects, nil)
+	if closeErr := rejects.Close(); closeErr != nil {
+		fmt.Printf("Reject file error: %v\n", closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRejectThreshold(rejects.Total(), cfg.ImportMaxRejects); err != nil {
+		return nil, fmt.Errorf("%v (%s), update not applied", err, rejects.Summary())
+	}
+
+	result := &DeltaResult{File: filepath.Base(path), Table: tableName, Rows: totalLines, Rejects: rejects.Summary()}
+	if err := mergeDelta(db, result, stagingTable, spec, cleanHeaders); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Merged %s: %d inserted, %d updated, %d skipped\n",
+		tableName, result.Inserted, result.Updated, result.Skipped)
+	return result, nil
+}
+
+// mergeDelta upserts the latest version of each key from the staging table
+// into the live table inside one transaction, so readers keep seeing either
+// the old or the new row and never an empty table.
+func mergeDelta(db *sql.DB, result *DeltaResult, stagingTable string, spec deltaTable, columns []string) error {
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin merge: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	live := result.Table
+	columnList := strings.Join(columns, ", ")
+
+	steps := []string{
+		fmt.Sprintf(`CREATE TEMP TABLE delta_latest ON COMMIT DROP AS
+			SELECT DISTINCT ON (%[1]s) %[3]s FROM %[4]s
+			ORDER BY %[1]s, %[2]s DESC NULLS LAST`, spec.Key, spec.Version, columnList, stagingTable),
+		fmt.Sprintf("CREATE INDEX ON delta_latest (%s)", spec.Key),
+	}
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step); err != nil {
+			return fmt.Errorf("prepare delta: %w", err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM delta_latest d
+		WHERE EXISTS (SELECT 1 FROM %[1]s t WHERE t.%[2]s = d.%[2]s AND t.%[3]s > d.%[3]s)`,
+		live, spec.Key, spec.Version))
+	if err != nil {
+		return fmt.Errorf("discard stale rows: %w", err)
+	}
+	result.Skipped, _ = res.RowsAffected()
+
+	var runID int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO update_runs (file_name, table_name, rows_in_file, skipped)
+		VALUES ($1, $2, $3, $4) RETURNING id`,
+		result.File, live, result.Rows, result.Skipped).Scan(&runID)
+	if err != nil {
+		return fmt.Errorf("record update run: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO update_changes (run_id, key_value, action, version)
+		SELECT $1, d.%[2]s,
+			CASE WHEN EXISTS (SELECT 1 FROM %[1]s t WHERE t.%[2]s = d.%[2]s) THEN 'update' ELSE 'insert' END,
+			d.%[3]s::text
+		FROM delta_latest d`, live, spec.Key, spec.Version), runID)
+	if err != nil {
+		return fmt.Errorf("record changes: %w", err)
+	}
+
+	// The counts come from the keys recorded above rather than from the
+	// DELETE and INSERT row counts, which a duplicated key would skew.
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FILTER (WHERE action = 'insert'), COUNT(*) FILTER (WHERE action = 'update')
+		FROM update_changes WHERE run_id = $1`, runID).Scan(&result.Inserted, &result.Updated)
+	if err != nil {
+		return fmt.Errorf("count changes: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %[1]s t USING delta_latest d WHERE t.%[2]s = d.%[2]s`,
+		live, spec.Key)); err != nil {
+		return fmt.Errorf("remove previous versions: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM delta_latest",
+		live, columnList, columnList)); err != nil {
+		return fmt.Errorf("insert new versions: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE update_runs SET inserted = $2, updated = $3, finished_at = now() WHERE id = $1`,
+		runID, result.Inserted, result.Updated)
+	if err != nil {
+		return fmt.Errorf("finalize update run: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit merge: %w", err)
+	}
+	return nil
+}
+
+func setupUpdateLog(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS update_runs (
+			id BIGSERIAL PRIMARY KEY,
+			file_name TEXT NOT NULL,
+			table_name TEXT NOT NULL,
+			rows_in_file INTEGER NOT NULL DEFAULT 0,
+			inserted BIGINT NOT NULL DEFAULT 0,
+			updated BIGINT NOT NULL DEFAULT 0,
+			skipped BIGINT NOT NULL DEFAULT 0,
+			started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			finished_at TIMESTAMPTZ
+		)`,
+		`CREATE TABLE IF NOT EXISTS update_changes (
+			run_id BIGINT NOT NULL REFERENCES update_runs(id) ON DELETE CASCADE,
+			key_value TEXT NOT NULL,
+			action TEXT NOT NULL,
+			version TEXT
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_update_changes_key ON update_changes(key_value)",
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("error creating update log: %v", err)
+		}
+	}
+	return nil
+}
@@ -1,37 +1,124 @@
 package csv
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 )
 
-var indexes = []struct {
-	name  string
-	query string
-}{
-	{"idx_etab_siren", "CREATE INDEX IF NOT EXISTS idx_etab_siren ON etablissement(siren)"},
-	{"idx_etab_siege", "CREATE INDEX IF NOT EXISTS idx_etab_siege ON etablissement(etablissement_siege)"},
-	{"idx_etab_naf", "CREATE INDEX IF NOT EXISTS idx_etab_naf ON etablissement(activite_principale_etablissement)"},
-	{"idx_etab_cp", "CREATE INDEX IF NOT EXISTS idx_etab_cp ON etablissement(code_postal_etablissement)"},
-	{"idx_etab_commune_trgm", "CREATE INDEX IF NOT EXISTS idx_etab_commune_trgm ON etablissement USING gin(libelle_commune_etablissement gin_trgm_ops)"},
-	{"idx_etab_siret", "CREATE INDEX IF NOT EXISTS idx_etab_siret ON etablissement(siret)"},
-	{"idx_etab_siege_siren", "CREATE INDEX IF NOT EXISTS idx_etab_siege_siren ON etablissement(siren) WHERE etablissement_siege = 'true'"},
-	{"idx_etab_siege_naf", "CREATE INDEX IF NOT EXISTS idx_etab_siege_naf ON etablissement(activite_principale_etablissement, siren) WHERE etablissement_siege = 'true'"},
-	{"idx_etab_siege_cp", "CREATE INDEX IF NOT EXISTS idx_etab_siege_cp ON etablissement(code_postal_etablissement, siren) WHERE etablissement_siege = 'true'"},
-	{"idx_ul_siren", "CREATE INDEX IF NOT EXISTS idx_ul_siren ON unite_legale(siren)"},
-	{"idx_ul_etat", "CREATE INDEX IF NOT EXISTS idx_ul_etat ON unite_legale(etat_administratif_unite_legale)"},
-	{"idx_ul_date", "CREATE INDEX IF NOT EXISTS idx_ul_date ON unite_legale(date_creation_unite_legale)"},
-	{"idx_ul_denom_trgm", "CREATE INDEX IF NOT EXISTS idx_ul_denom_trgm ON unite_legale USING gin(denomination_unite_legale gin_trgm_ops)"},
-	{"idx_ul_siren_date", "CREATE INDEX IF NOT EXISTS idx_ul_siren_date ON unite_legale(siren, date_creation_unite_legale DESC)"},
-	{"idx_naf_ref_label_trgm", "CREATE INDEX IF NOT EXISTS idx_naf_ref_label_trgm ON naf_reference USING gin(label gin_trgm_ops)"},
-	{"idx_naf_ref_section", "CREATE INDEX IF NOT EXISTS idx_naf_ref_section ON naf_reference(section_code)"},
-	{"idx_ul_denom_unaccent_trgm", "CREATE INDEX IF NOT EXISTS idx_ul_denom_unaccent_trgm ON unite_legale USING gin(immutable_unaccent(denomination_unite_legale) gin_trgm_ops)"},
-	{"idx_etab_commune_unaccent_trgm", "CREATE INDEX IF NOT EXISTS idx_etab_commune_unaccent_trgm ON etablissement USING gin(immutable_unaccent(libelle_commune_etablissement) gin_trgm_ops)"},
-	{"idx_naf_label_unaccent_trgm", "CREATE INDEX IF NOT EXISTS idx_naf_label_unaccent_trgm ON naf_reference USING gin(immutable_unaccent(label) gin_trgm_ops)"},
-}
-
-func CreateIndexes(db *sql.DB) error {
+const (
+	INDEX_CREATED = "created"
+	INDEX_REBUILT = "rebuilt"
+	INDEX_PRESENT = "present"
+	INDEX_FAILED  = "failed"
+
+	INDEX_VALID   = "valid"
+	INDEX_INVALID = "invalid"
+	INDEX_MISSING = "missing"
+
+	INDEX_PROGRESS_INTERVAL = 15 * time.Second
+)
+
+type indexDef struct {
+	name       string
+	table      string
+	definition string
+}
+
+var indexes = []indexDef{
+	{"idx_etab_siren", "etablissement", "(siren)"},
+	{"idx_etab_siege", "etablissement", "(etablissement_siege)"},
+	{"idx_etab_naf", "etablissement", "(activite_principale_etablissement)"},
+	{"idx_etab_cp", "etablissement", "(code_postal_etablissement)"},
+	{"idx_etab_commune_trgm", "etablissement", "USING gin(libelle_commune_etablissement gin_trgm_ops)"},
+	{"idx_etab_siret_key", "etablissement", "(siret)"},
+	{"idx_etab_siege_siren", "etablissement", "(siren) WHERE etablissement_siege"},
+	{"idx_etab_siege_naf", "etablissement", "(activite_principale_etablissement, siren) WHERE etablissement_siege"},
+	{"idx_etab_siege_cp", "etablissement", "(code_postal_etablissement, siren) WHERE etablissement_siege"},
+	{"idx_ul_siren_key", "unite_legale", "(siren)"},
+	{"idx_ul_etat", "unite_legale", "(etat_administratif_unite_legale)"},
+	{"idx_ul_date", "unite_legale", "(date_creation_unite_legale)"},
+	{"idx_ul_denom_trgm", "unite_legale", "USING gin(denomination_unite_legale gin_trgm_ops)"},
+	{"idx_ul_siren_date", "unite_legale", "(siren, date_creation_unite_legale DESC)"},
+	{"idx_ul_date_siren_keyset", "unite_legale", "((COALESCE(date_creation_unite_legale, DATE '0001-01-01')) DESC, siren DESC)"},
+	{"idx_naf_ref_label_trgm", "naf_reference", "USING gin(label gin_trgm_ops)"},
+	{"idx_naf_ref_section", "naf_reference", "(section_code)"},
+	{"idx_ul_denom_unaccent_trgm", "unite_legale", "USING gin(immutable_unaccent(denomination_unite_legale) gin_trgm_ops)"},
+	{"idx_etab_commune_unaccent_trgm", "etablissement", "USING gin(immutable_unaccent(libelle_commune_etablissement) gin_trgm_ops)"},
+	{"idx_naf_label_unaccent_trgm", "naf_reference", "USING gin(immutable_unaccent(label) gin_trgm_ops)"},
+	{"idx_ul_search_vector", "unite_legale", "USING gin(search_vector)"},
+	{"idx_etab_search_vector", "etablissement", "USING gin(search_vector)"},
+	{"idx_ul_suggest", "unite_legale", `((immutable_unaccent(upper(denomination_unite_legale))) COLLATE "C", siren) WHERE etat_administratif_unite_legale = 'A' AND COALESCE(statut_diffusion_unite_legale, 'O') = 'O'`},
+}
+
+// uniqueIndexes keep a single row per SIREN and SIRET, which the delete and
+// insert of a daily update relies on.
+var uniqueIndexes = map[string]bool{
+	"idx_ul_siren_key":   true,
+	"idx_etab_siret_key": true,
+}
+
+// retiredIndexes were replaced by a catalog entry under another name. They
+// are dropped from the live tables once the catalog is built, so a failed
+// unique build leaves the old index in place.
+var retiredIndexes = []string{"idx_ul_siren", "idx_etab_siret"}
+
+// IndexOptions controls how the catalog is built. CONCURRENTLY keeps the
+// table writable during the build but cannot run in a transaction and
+// serializes builds on the same table, so workers then take a whole table.
+type IndexOptions struct {
+	Workers      int
+	Concurrently bool
+}
+
+// IndexInfo is the state of a catalog index in the database.
+type IndexInfo struct {
+	Name       string `json:"name"`
+	Table      string `json:"table"`
+	Definition string `json:"definition"`
+	Status     string `json:"status"`
+	SizeBytes  int64  `json:"size_bytes"`
+}
+
+// IndexBuild is a running CREATE INDEX as reported by
+// pg_stat_progress_create_index.
+type IndexBuild struct {
+	PID         int     `json:"pid"`
+	Index       string  `json:"index,omitempty"`
+	Table       string  `json:"table"`
+	Command     string  `json:"command"`
+	Phase       string  `json:"phase"`
+	BlocksDone  int64   `json:"blocks_done"`
+	BlocksTotal int64   `json:"blocks_total"`
+	TuplesDone  int64   `json:"tuples_done"`
+	TuplesTotal int64   `json:"tuples_total"`
+	Percent     float64 `json:"percent"`
+	ElapsedSec  int64   `json:"elapsed_sec"`
+}
+
+type indexResult struct {
+	name    string
+	status  string
+	elapsed time.Duration
+	err     error
+}
+
+func (idx indexDef) query(suffix string, concurrently bool) string {
+	create := "CREATE INDEX"
+	if uniqueIndexes[idx.name] {
+		create = "CREATE UNIQUE INDEX"
+	}
+	if concurrently {
+		create += " CONCURRENTLY"
+	}
+	return fmt.Sprintf("%s IF NOT EXISTS %s%s ON %s%s %s", create, idx.name, suffix, idx.table, suffix, idx.definition)
+}
+
+func prepareIndexFunctions(db *sql.DB) error {
 	fmt.Println("Activation de l'extension pg_trgm...")
 	if _, err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm"); err != nil {
 		return fmt.Errorf("pg_trgm: %w", err)
@@ -49,26 +136,313 @@ func CreateIndexes(db *sql.DB) error {
 		return fmt.Errorf("immutable_unaccent: %w", err)
 	}
 
-	fmt.Printf("Creation de %d indexes...\n", len(indexes))
-	totalStart := time.Now()
-	errCount := 0
+	return nil
+}
 
-	for i, idx := range indexes {
-		start := time.Now()
-		fmt.Printf("[%d/%d] %s... ", i+1, len(indexes), idx.name)
+// CreateIndexes builds the catalog on the live tables. Valid indexes are
+// kept, invalid ones left by an interrupted build are dropped and rebuilt,
+// and tables that do not exist yet are skipped. Tables imported before the
+// full-text column existed get it first, and retired indexes are dropped
+// last.
+func CreateIndexes(db *sql.DB, opts IndexOptions) error {
+	var selected []indexDef
+	for _, idx := range indexes {
+		exists, err := tableExists(context.Background(), db, idx.table)
+		if err != nil {
+			return fmt.Errorf("check %s: %w", idx.table, err)
+		}
+		if !exists {
+			fmt.Printf("%s ignore, la table %s n'existe pas\n", idx.name, idx.table)
+			continue
+		}
+		selected = append(selected, idx)
+	}
 
-		if _, err := db.Exec(idx.query); err != nil {
-			fmt.Printf("ERREUR: %v\n", err)
-			errCount++
+	for table := range searchVectorSources {
+		exists, err := tableExists(context.Background(), db, table)
+		if err != nil {
+			return fmt.Errorf("check %s: %w", table, err)
+		}
+		if exists {
+			if err := addSearchVector(db, table); err != nil {
+				return err
+			}
+		}
+	}
+	if err := createIndexes(db, selected, "", opts); err != nil {
+		return err
+	}
+	return dropRetiredIndexes(db)
+}
+
+func dropRetiredIndexes(db *sql.DB) error {
+	for _, name := range retiredIndexes {
+		state, err := indexState(db, name)
+		if err != nil {
+			return err
+		}
+		if state == INDEX_MISSING {
 			continue
 		}
+		fmt.Printf("Suppression de l'index remplace %s...\n", name)
+		if _, err := db.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + name); err != nil {
+			return fmt.Errorf("drop %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// CreateStagingIndexes builds the indexes of the given tables on their
+// __staging copy, so they are ready before the swap. Nothing reads or writes
+// the staging tables, so the builds do not need CONCURRENTLY.
+func CreateStagingIndexes(db *sql.DB, tables []string, workers int) error {
+	var selected []indexDef
+	for _, idx := range indexes {
+		if slices.Contains(tables, idx.table) {
+			selected = append(selected, idx)
+		}
+	}
+	return createIndexes(db, selected, STAGING_SUFFIX, IndexOptions{Workers: workers})
+}
 
-		fmt.Printf("OK (%.1fs)\n", time.Since(start).Seconds())
+func createIndexes(db *sql.DB, selected []indexDef, suffix string, opts IndexOptions) error {
+	if err := prepareIndexFunctions(db); err != nil {
+		return err
 	}
 
-	fmt.Printf("\nIndexes termines en %.1fs (%d/%d reussis)\n", time.Since(totalStart).Seconds(), len(indexes)-errCount, len(indexes))
-	if errCount > 0 {
-		return fmt.Errorf("%d indexes en echec sur %d", errCount, len(indexes))
+	jobs := indexJobs(selected, opts.Concurrently)
+	workers := max(1, min(opts.Workers, len(jobs)))
+	mode := "CREATE INDEX"
+	if opts.Concurrently {
+		mode = "CREATE INDEX CONCURRENTLY"
+	}
+	fmt.Printf("Creation de %d indexes (%s, %d workers)...\n", len(selected), mode, workers)
+
+	totalStart := time.Now()
+	queue := make(chan []indexDef)
+	done := make(chan struct{})
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	finished, failed := 0, 0
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				for _, idx := range job {
+					result := buildIndex(db, idx, suffix, opts.Concurrently)
+
+					mu.Lock()
+					finished++
+					if result.err != nil {
+						failed++
+						fmt.Printf("[%d/%d] %s: ERREUR: %v\n", finished, len(selected), result.name, result.err)
+					} else {
+						fmt.Printf("[%d/%d] %s: %s (%.1fs)\n", finished, len(selected), result.name, result.status, result.elapsed.Seconds())
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	go reportIndexProgress(db, done)
+
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+	close(done)
+
+	fmt.Printf("\nIndexes termines en %.1fs (%d/%d reussis)\n", time.Since(totalStart).Seconds(), len(selected)-failed, len(selected))
+	if failed > 0 {
+		return fmt.Errorf("%d indexes en echec sur %d", failed, len(selected))
 	}
 	return nil
 }
+
+// indexJobs splits the catalog into units of work. Plain builds on the same
+// table can run side by side; concurrent ones wait for each other, so a
+// table is then a single job.
+func indexJobs(selected []indexDef, concurrently bool) [][]indexDef {
+	var jobs [][]indexDef
+	if !concurrently {
+		for _, idx := range selected {
+			jobs = append(jobs, []indexDef{idx})
+		}
+		return jobs
+	}
+
+	byTable := make(map[string]int)
+	for _, idx := range selected {
+		i, ok := byTable[idx.table]
+		if !ok {
+			i = len(jobs)
+			byTable[idx.table] = i
+			jobs = append(jobs, nil)
+		}
+		jobs[i] = append(jobs[i], idx)
+	}
+	return jobs
+}
+
+// buildIndex creates one index. IF NOT EXISTS alone would keep an invalid
+// index left by a failed CONCURRENTLY build, so its state is checked first.
+func buildIndex(db *sql.DB, idx indexDef, suffix string, concurrently bool) indexResult {
+	start := time.Now()
+	result := indexResult{name: idx.name + suffix, status: INDEX_CREATED}
+
+	state, err := indexState(db, result.name)
+	if err != nil {
+		result.status, result.err = INDEX_FAILED, err
+		return result
+	}
+
+	switch state {
+	case INDEX_VALID:
+		result.status = INDEX_PRESENT
+		return result
+	case INDEX_INVALID:
+		drop := "DROP INDEX IF EXISTS " + result.name
+		if concurrently {
+			drop = "DROP INDEX CONCURRENTLY IF EXISTS " + result.name
+		}
+		if _, err := db.Exec(drop); err != nil {
+			result.status, result.err = INDEX_FAILED, fmt.Errorf("drop invalid index: %w", err)
+			return result
+		}
+		result.status = INDEX_REBUILT
+	}
+
+	if _, err := db.Exec(idx.query(suffix, concurrently)); err != nil {
+		result.status, result.err = INDEX_FAILED, err
+		return result
+	}
+
+	result.elapsed = time.Since(start)
+	return result
+}
+
+func indexState(db *sql.DB, name string) (string, error) {
+	var valid bool
+	err := db.QueryRow(`SELECT i.indisvalid FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema() AND c.relname = $1`, name).Scan(&valid)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return INDEX_MISSING, nil
+	case err != nil:
+		return "", fmt.Errorf("read state of %s: %w", name, err)
+	case !valid:
+		return INDEX_INVALID, nil
+	}
+	return INDEX_VALID, nil
+}
+
+func reportIndexProgress(db *sql.DB, done <-chan struct{}) {
+	ticker := time.NewTicker(INDEX_PROGRESS_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			builds, err := IndexProgress(context.Background(), db)
+			if err != nil {
+				continue
+			}
+			for _, b := range builds {
+				fmt.Printf("   en cours: %s %s, %s %.1f%% (%ds)\n", b.Table, b.Index, b.Phase, b.Percent, b.ElapsedSec)
+			}
+		}
+	}
+}
+
+// IndexStatus reports every catalog index of the live tables as valid,
+// invalid or missing, with its size on disk.
+func IndexStatus(ctx context.Context, db *sql.DB) ([]IndexInfo, error) {
+	names := make([]string, len(indexes))
+	for i, idx := range indexes {
+		names[i] = idx.name
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT c.relname, i.indisvalid, pg_relation_size(c.oid)
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema() AND c.relname = ANY($1)`, names)
+	if err != nil {
+		return nil, fmt.Errorf("read index status: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	type state struct {
+		valid bool
+		size  int64
+	}
+	found := make(map[string]state)
+	for rows.Next() {
+		var name string
+		var s state
+		if err := rows.Scan(&name, &s.valid, &s.size); err != nil {
+			return nil, err
+		}
+		found[name] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	infos := make([]IndexInfo, 0, len(indexes))
+	for _, idx := range indexes {
+		info := IndexInfo{Name: idx.name, Table: idx.table, Definition: idx.definition, Status: INDEX_MISSING}
+		if s, ok := found[idx.name]; ok {
+			info.Status = INDEX_VALID
+			if !s.valid {
+				info.Status = INDEX_INVALID
+			}
+			info.SizeBytes = s.size
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// IndexProgress lists the index builds running in the current database.
+// Plain CREATE INDEX only reports its index name once the build is done.
+func IndexProgress(ctx context.Context, db *sql.DB) ([]IndexBuild, error) {
+	rows, err := db.QueryContext(ctx, `SELECT p.pid, COALESCE(ic.relname, ''), COALESCE(tc.relname, ''),
+			p.command, p.phase, p.blocks_done, p.blocks_total, p.tuples_done, p.tuples_total,
+			COALESCE(EXTRACT(EPOCH FROM now() - a.query_start), 0)::bigint
+		FROM pg_stat_progress_create_index p
+		LEFT JOIN pg_class tc ON tc.oid = p.relid
+		LEFT JOIN pg_class ic ON ic.oid = p.index_relid
+		LEFT JOIN pg_stat_activity a ON a.pid = p.pid
+		WHERE p.datname = current_database()
+		ORDER BY p.pid`)
+	if err != nil {
+		return nil, fmt.Errorf("read index progress: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	builds := []IndexBuild{}
+	for rows.Next() {
+		var b IndexBuild
+		if err := rows.Scan(&b.PID, &b.Index, &b.Table, &b.Command, &b.Phase,
+			&b.BlocksDone, &b.BlocksTotal, &b.TuplesDone, &b.TuplesTotal, &b.ElapsedSec); err != nil {
+			return nil, err
+		}
+		switch {
+		case b.BlocksTotal > 0:
+			b.Percent = float64(b.BlocksDone) * 100 / float64(b.BlocksTotal)
+		case b.TuplesTotal > 0:
+			b.Percent = float64(b.TuplesDone) * 100 / float64(b.TuplesTotal)
+		}
+		builds = append(builds, b)
+	}
+	return builds, rows.Err()
+}
@@ -3,28 +3,45 @@ package csv
 import (
 	"bufio"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"runtime"
+	"sirene-importer/config"
+	"sirene-importer/database"
 	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func ProcessPipelineFromReader(reader io.Reader, tableName string, headers []string) (int, error) {
+func ProcessPipelineFromReader(reader io.Reader, tableName string, schema tableSchema, rejects *RejectWriter, cp *fileCheckpoint) (int, error) {
 	numWorkers := MinInt(runtime.NumCPU(), 8)
 
 	fmt.Printf("Using %d workers (CPU cores: %d)\n", numWorkers, runtime.NumCPU())
 
-	recordChan := make(chan []string, 10000)
+	pool, err := database.NewPgxPool(config.Load(), numWorkers)
+	if err != nil {
+		return 0, err
+	}
+	defer pool.Close()
+
+	batchChan := make(chan recordBatch, numWorkers)
 	resultChan := make(chan int, numWorkers)
 
 	var wg sync.WaitGroup
 
+	// The reader's error is read once resultChan is closed, which happens
+	// after wg.Wait, so it needs no lock.
+	var readErr error
 	wg.Add(1)
-	go streamFromReader(reader, recordChan, &wg)
+	go func() {
+		defer wg.Done()
+		readErr = streamFromReader(reader, batchChan, rejects, cp)
+	}()
 
 	for i := range numWorkers {
 		wg.Add(1)
-		go streamWorker(i, tableName, headers, recordChan, resultChan, &wg)
+		go streamWorker(i, pool, tableName, schema, batchChan, resultChan, rejects, cp, &wg)
 	}
 
 	go func() {
@@ -36,72 +53,132 @@ func ProcessPipelineFromReader(reader io.Reader, tableName string, headers []str
 	for count := range resultChan {
 		totalLines += count
 	}
+	if readErr != nil {
+		return totalLines, fmt.Errorf("read %s: %w", tableName, readErr)
+	}
 
 	return totalLines, nil
 }
 
-func streamFromReader(reader io.Reader, recordChan chan<- []string, wg *sync.WaitGroup) {
-	defer wg.Done()
-	defer close(recordChan)
+// streamFromReader groups consecutive records into numbered batches of
+// BATCH_SIZE; batches already committed by a previous attempt are skipped.
+func streamFromReader(reader io.Reader, batchChan chan<- recordBatch, rejects *RejectWriter, cp *fileCheckpoint) error {
+	defer close(batchChan)
 
-	bufferedReader := bufio.NewReaderSize(reader, 4*1024*1024)
+	capture := &captureReader{r: reader}
+	bufferedReader := bufio.NewReaderSize(capture, 4*1024*1024)
 	csvReader := csv.NewReader(bufferedReader)
 	csvReader.Comma = ','
 	csvReader.ReuseRecord = true
 	csvReader.LazyQuotes = true
 
-	_, _ = csvReader.Read()
+	if _, err := csvReader.Read(); err != nil && !isParseOrEOF(err) {
+		return err
+	}
 
 	lineCount := 0
+	skipped := 0
+	batch := recordBatch{Records: make([]csvRecord, 0, BATCH_SIZE)}
+
+	// Malformed lines are held with their batch and only written when the
+	// batch is not already committed, so a resume does not report them twice.
+	var pending []parseReject
+	emit := func(endOffset int64) {
+		batch.EndOffset = endOffset
+		batch.ParseRejects = len(pending)
+		if cp.isCommitted(batch.Index) {
+			skipped += len(batch.Records)
+			batch.Records = batch.Records[:0]
+		} else {
+			for _, r := range pending {
+				rejects.Add(REJECT_PARSE, r.line, r.raw, r.err)
+			}
+			batchChan <- batch
+			batch.Records = make([]csvRecord, 0, BATCH_SIZE)
+		}
+		pending = pending[:0]
+		batch.Index++
+	}
+
 	for {
+		start := csvReader.InputOffset()
 		record, err := csvReader.Read()
+		end := csvReader.InputOffset()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
+
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return err
+			}
+			pending = append(pending, parseReject{line: parseErr.StartLine, raw: capture.raw(start, end), err: err})
+			capture.release(end)
 			continue
 		}
 
+		line, _ := csvReader.FieldPos(0)
+		capture.release(end)
+
 		recordCopy := make([]string, len(record))
 		copy(recordCopy, record)
 
-		recordChan <- recordCopy
+		batch.Records = append(batch.Records, csvRecord{Line: line, Fields: recordCopy})
 		lineCount++
 
+		if len(batch.Records) >= BATCH_SIZE {
+			emit(end)
+		}
+
 		if lineCount%2000000 == 0 {
 			fmt.Printf("Streamed: %.1fM lines\n", float64(lineCount)/1000000)
 		}
 	}
 
+	if len(batch.Records) > 0 {
+		emit(csvReader.InputOffset())
+	}
+	for _, r := range pending {
+		rejects.Add(REJECT_PARSE, r.line, r.raw, r.err)
+	}
+
+	if skipped > 0 {
+		fmt.Printf("Reprise: %d lignes deja importees ignorees\n", skipped)
+	}
 	fmt.Printf("Reader finished: %.1fM lines\n", float64(lineCount)/1000000)
+	return nil
+}
+
+type parseReject struct {
+	line int
+	raw  string
+	err  error
+}
+
+func isParseOrEOF(err error) bool {
+	var parseErr *csv.ParseError
+	return err == io.EOF || errors.As(err, &parseErr)
 }
 
-func streamWorker(workerID int, tableName string, headers []string, recordChan <-chan []string, resultChan chan<- int, wg *sync.WaitGroup) {
+func streamWorker(workerID int, pool *pgxpool.Pool, tableName string, schema tableSchema, batchChan <-chan recordBatch, resultChan chan<- int, rejects *RejectWriter, cp *fileCheckpoint, wg *sync.WaitGroup) {
 	defer wg.Done()
 
+	worker := &copyWorker{pool: pool}
+	defer worker.release()
+
 	lineCount := 0
-	batchSize := 200000
-	batch := make([][]string, 0, batchSize)
 
 	fmt.Printf("Worker %d started\n", workerID)
 
-	for record := range recordChan {
-		batch = append(batch, record)
-
-		if len(batch) >= batchSize {
-			if err := InsertBatch(tableName, headers, batch); err != nil {
-				fmt.Printf("Worker %d batch error: %v\n", workerID, err)
-				continue
-			}
-			lineCount += len(batch)
-			batch = batch[:0]
-		}
-	}
-
-	if len(batch) > 0 {
-		if err := InsertBatch(tableName, headers, batch); err == nil {
-			lineCount += len(batch)
+	for batch := range batchChan {
+		rows := schema.convertBatch(batch, rejects)
+		if err := worker.insertRecordBatch(tableName, schema.Headers, batch, rows, cp); err != nil {
+			fmt.Printf("Worker %d batch error (%d rows rejected): %v\n", workerID, len(rows), err)
+			rejects.AddBatch(batch.Records, err)
+			continue
 		}
+		lineCount += len(rows)
 	}
 
 	resultChan <- lineCount
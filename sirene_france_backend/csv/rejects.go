@@ -0,0 +1,218 @@
+package csv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	REJECT_PARSE   = "parse"
+	REJECT_CONVERT = "convert"
+	REJECT_BATCH   = "batch"
+
+	// Rows with an invalid identifier are loaded anyway and only flagged.
+	FLAG_IDENTIFIER = "identifier"
+)
+
+type csvRecord struct {
+	Line   int
+	Fields []string
+}
+
+// RejectWriter quarantines rows that could not be loaded into a CSV file
+// (line, kind, error, raw) next to the import. The file is only created on
+// the first reject.
+type RejectWriter struct {
+	mu     sync.Mutex
+	dir    string
+	table  string
+	path   string
+	file   *os.File
+	writer *csv.Writer
+	counts map[string]int
+	flags  int
+}
+
+func NewRejectWriter(dir, table string) *RejectWriter {
+	return &RejectWriter{dir: dir, table: table, counts: make(map[string]int)}
+}
+
+func (w *RejectWriter) open() error {
+	if w.writer != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(w.dir, 0o755); err != nil {
+		return err
+	}
+
+	w.path = filepath.Join(w.dir, fmt.Sprintf("%s_%s.csv", w.table, time.Now().Format("20060102_150405")))
+	file, err := os.Create(w.path)
+	if err != nil {
+		return err
+	}
+
+	w.file = file
+	w.writer = csv.NewWriter(file)
+	return w.writer.Write([]string{"line", "kind", "error", "raw"})
+}
+
+func (w *RejectWriter) write(kind string, line int, raw string, cause error) {
+	w.counts[kind]++
+	w.writeRow(kind, line, raw, cause)
+}
+
+func (w *RejectWriter) writeRow(kind string, line int, raw string, cause error) {
+	if err := w.open(); err != nil {
+		fmt.Printf("Reject file error: %v\n", err)
+		return
+	}
+
+	if err := w.writer.Write([]string{strconv.Itoa(line), kind, cause.Error(), raw}); err != nil {
+		fmt.Printf("Reject write error: %v\n", err)
+	}
+}
+
+func (w *RejectWriter) Add(kind string, line int, raw string, cause error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.write(kind, line, raw, cause)
+}
+
+// Flag records a loaded row whose identifier failed its checksum. Flags go
+// to the same file but do not count as rejects.
+func (w *RejectWriter) Flag(line int, raw string, cause error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.flags++
+	w.writeRow(FLAG_IDENTIFIER, line, raw, cause)
+}
+
+func (w *RejectWriter) Flagged() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.flags
+}
+
+func (w *RejectWriter) AddBatch(batch []csvRecord, cause error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, record := range batch {
+		w.write(REJECT_BATCH, record.Line, encodeRecord(record.Fields), cause)
+	}
+}
+
+func (w *RejectWriter) Counts() map[string]int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	counts := make(map[string]int, len(w.counts))
+	for k, v := range w.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+func (w *RejectWriter) Total() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	total := 0
+	for _, v := range w.counts {
+		total += v
+	}
+	return total
+}
+
+func (w *RejectWriter) Path() string {
+	return w.path
+}
+
+func (w *RejectWriter) Summary() string {
+	total := w.Total()
+	flagged := w.Flagged()
+	if total == 0 && flagged == 0 {
+		return "0 rejets"
+	}
+	if total == 0 {
+		return fmt.Sprintf("0 rejets, %d identifiants invalides -> %s", flagged, w.path)
+	}
+
+	counts := w.Counts()
+	return fmt.Sprintf("%d rejets (parse: %d, convert: %d, batch: %d), %d identifiants invalides -> %s",
+		total, counts[REJECT_PARSE], counts[REJECT_CONVERT], counts[REJECT_BATCH], flagged, w.path)
+}
+
+func (w *RejectWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.writer == nil {
+		return nil
+	}
+
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		_ = w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+func encodeRecord(fields []string) string {
+	var sb strings.Builder
+	writer := csv.NewWriter(&sb)
+	_ = writer.Write(fields)
+	writer.Flush()
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// captureReader keeps the bytes read from the underlying stream since the
+// last released offset, so a malformed record can be quarantined verbatim.
+type captureReader struct {
+	r    io.Reader
+	buf  []byte
+	base int64
+}
+
+func (c *captureReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.buf = append(c.buf, p[:n]...)
+	return n, err
+}
+
+func (c *captureReader) raw(from, to int64) string {
+	start := max(from-c.base, 0)
+	end := min(to-c.base, int64(len(c.buf)))
+	if start >= end {
+		return ""
+	}
+	return strings.TrimRight(string(c.buf[start:end]), "\r\n")
+}
+
+func (c *captureReader) release(upTo int64) {
+	n := upTo - c.base
+	if n < 1<<20 || n > int64(len(c.buf)) {
+		return
+	}
+	c.buf = append(c.buf[:0], c.buf[n:]...)
+	c.base = upTo
+}
+
+func checkRejectThreshold(total, maxRejects int) error {
+	if total > maxRejects {
+		return fmt.Errorf("%d rejected rows exceed IMPORT_MAX_REJECTS=%d", total, maxRejects)
+	}
+	return nil
+}
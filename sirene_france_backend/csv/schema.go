@@ -0,0 +1,232 @@
+package csv
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sirene-importer/identifier"
+)
+
+const (
+	COL_TEXT      = "TEXT"
+	COL_DATE      = "DATE"
+	COL_TIMESTAMP = "TIMESTAMP"
+	COL_INTEGER   = "INTEGER"
+	COL_BOOLEAN   = "BOOLEAN"
+)
+
+// tableSchemas lists the typed columns of each table, any other column is
+// TEXT. Identifiers and codes (siren, siret, code postal, NAF) stay TEXT to
+// keep their leading zeros.
+var tableSchemas = map[string]map[string]string{
+	"unite_legale": {
+		"unite_purgee_unite_legale":            COL_BOOLEAN,
+		"date_creation_unite_legale":           COL_DATE,
+		"annee_effectifs_unite_legale":         COL_INTEGER,
+		"date_dernier_traitement_unite_legale": COL_TIMESTAMP,
+		"nombre_periodes_unite_legale":         COL_INTEGER,
+		"annee_categorie_entreprise":           COL_INTEGER,
+		"date_debut":                           COL_DATE,
+	},
+	"etablissement": {
+		"date_creation_etablissement":           COL_DATE,
+		"annee_effectifs_etablissement":         COL_INTEGER,
+		"date_dernier_traitement_etablissement": COL_TIMESTAMP,
+		"etablissement_siege":                   COL_BOOLEAN,
+		"nombre_periodes_etablissement":         COL_INTEGER,
+		"date_debut":                            COL_DATE,
+	},
+}
+
+var (
+	dateLayouts      = []string{"2006-01-02"}
+	timestampLayouts = []string{"2006-01-02T15:04:05.000", "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+)
+
+// identifierColumns lists the columns holding a SIREN or SIRET. A number
+// that fails its Luhn check is loaded as is and flagged in the reject file.
+var identifierColumns = map[string]map[string]func(string) error{
+	"unite_legale":  {"siren": validSiren},
+	"etablissement": {"siren": validSiren, "siret": validSiret},
+}
+
+func validSiren(value string) error {
+	_, err := identifier.NormalizeSiren(value)
+	return err
+}
+
+func validSiret(value string) error {
+	_, err := identifier.NormalizeSiret(value)
+	return err
+}
+
+// tableSchema is the COPY column list of a file with the type of each column.
+type tableSchema struct {
+	Headers []string
+	Types   []string
+	checks  []identifierCheck
+}
+
+type identifierCheck struct {
+	index int
+	valid func(string) error
+}
+
+// identifierChecks returns the identifier checks of table for its headers.
+func identifierChecks(table string, headers []string) []identifierCheck {
+	var checks []identifierCheck
+	for i, header := range headers {
+		if valid, ok := identifierColumns[table][header]; ok {
+			checks = append(checks, identifierCheck{index: i, valid: valid})
+		}
+	}
+	return checks
+}
+
+func columnType(table, column string) string {
+	if typ, ok := tableSchemas[table][column]; ok {
+		return typ
+	}
+	return COL_TEXT
+}
+
+// liveSchema takes the column types from an existing table, so files merged
+// into it are converted the way that table was created.
+func liveSchema(db *sql.DB, table string, headers []string) (tableSchema, error) {
+	rows, err := db.Query(`SELECT column_name, data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1`, table)
+	if err != nil {
+		return tableSchema{}, fmt.Errorf("read columns of %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	dataTypes := make(map[string]string)
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return tableSchema{}, err
+		}
+		dataTypes[name] = dataType
+	}
+	if err := rows.Err(); err != nil {
+		return tableSchema{}, err
+	}
+
+	schema := tableSchema{Headers: headers, Types: make([]string, len(headers)), checks: identifierChecks(table, headers)}
+	for i, header := range headers {
+		dataType, ok := dataTypes[header]
+		if !ok {
+			return tableSchema{}, fmt.Errorf("column %s does not exist in %s", header, table)
+		}
+
+		switch {
+		case dataType == "date":
+			schema.Types[i] = COL_DATE
+		case strings.HasPrefix(dataType, "timestamp"):
+			schema.Types[i] = COL_TIMESTAMP
+		case dataType == "integer" || dataType == "bigint" || dataType == "smallint":
+			schema.Types[i] = COL_INTEGER
+		case dataType == "boolean":
+			schema.Types[i] = COL_BOOLEAN
+		default:
+			schema.Types[i] = COL_TEXT
+		}
+	}
+	return schema, nil
+}
+
+// convertBatch turns the records of a batch into COPY rows. Records with a
+// value that does not match its column type are sent to the reject file.
+func (s tableSchema) convertBatch(batch recordBatch, rejects *RejectWriter) [][]any {
+	rows := make([][]any, 0, len(batch.Records))
+	for _, record := range batch.Records {
+		row, err := s.convertRecord(record.Fields)
+		if err != nil {
+			rejects.Add(REJECT_CONVERT, record.Line, encodeRecord(record.Fields), err)
+			continue
+		}
+		s.flagIdentifiers(record, rejects)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// flagIdentifiers writes the record to the reject file, without rejecting
+// it, when one of its identifiers is invalid. Empty identifiers are skipped.
+func (s tableSchema) flagIdentifiers(record csvRecord, rejects *RejectWriter) {
+	for _, check := range s.checks {
+		if check.index >= len(record.Fields) {
+			continue
+		}
+		field := strings.TrimSpace(record.Fields[check.index])
+		if field == "" {
+			continue
+		}
+		if err := check.valid(field); err != nil {
+			rejects.Flag(record.Line, encodeRecord(record.Fields), fmt.Errorf("column %s: %w", s.Headers[check.index], err))
+			return
+		}
+	}
+}
+
+func (s tableSchema) convertRecord(fields []string) ([]any, error) {
+	row := make([]any, len(fields))
+	for i, field := range fields {
+		if i >= len(s.Types) {
+			row[i] = field
+			continue
+		}
+
+		value, err := convertValue(s.Types[i], field)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", s.Headers[i], err)
+		}
+		row[i] = value
+	}
+	return row, nil
+}
+
+// convertValue parses a CSV field for its column type. Empty fields of typed
+// columns are NULL.
+func convertValue(typ, field string) (any, error) {
+	if typ == COL_TEXT {
+		return field, nil
+	}
+
+	field = strings.TrimSpace(field)
+	if field == "" {
+		return nil, nil
+	}
+
+	switch typ {
+	case COL_DATE:
+		return parseTime(field, dateLayouts, "date")
+	case COL_TIMESTAMP:
+		return parseTime(field, timestampLayouts, "timestamp")
+	case COL_INTEGER:
+		n, err := strconv.ParseInt(field, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", field)
+		}
+		return n, nil
+	case COL_BOOLEAN:
+		b, err := strconv.ParseBool(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", field)
+		}
+		return b, nil
+	}
+	return field, nil
+}
+
+func parseTime(field string, layouts []string, kind string) (time.Time, error) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, field); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q", kind, field)
+}
@@ -0,0 +1,96 @@
+package csv
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const SEARCH_VECTOR_COLUMN = "search_vector"
+
+type searchVectorSource struct {
+	column string
+	weight string
+}
+
+// searchVectorSources lists, per table, the text columns folded into its
+// generated full-text column: names and acronyms weigh most, usual names
+// next, then the commune. A generated column only sees its own row, so the
+// API combines the vectors of unite_legale and etablissement at query time.
+var searchVectorSources = map[string][]searchVectorSource{
+	"unite_legale": {
+		{"denomination_unite_legale", "A"},
+		{"sigle_unite_legale", "A"},
+		{"denomination_usuelle1_unite_legale", "B"},
+		{"denomination_usuelle2_unite_legale", "B"},
+		{"denomination_usuelle3_unite_legale", "B"},
+	},
+	"etablissement": {
+		{"enseigne1_etablissement", "A"},
+		{"denomination_usuelle_etablissement", "A"},
+		{"enseigne2_etablissement", "B"},
+		{"enseigne3_etablissement", "B"},
+		{"libelle_commune_etablissement", "C"},
+	},
+}
+
+// searchVectorDefinition returns the generated column definition of table
+// for the given columns, or "" when table has no search vector. A missing
+// source column is an error: the file layout changed and the vector would
+// silently lose a field.
+func searchVectorDefinition(table string, columns []string) (string, error) {
+	sources, ok := searchVectorSources[table]
+	if !ok {
+		return "", nil
+	}
+
+	parts := make([]string, 0, len(sources))
+	for _, source := range sources {
+		if !slices.Contains(columns, source.column) {
+			return "", fmt.Errorf("%s of %s: column %s not found", SEARCH_VECTOR_COLUMN, table, source.column)
+		}
+		parts = append(parts, fmt.Sprintf("setweight(to_tsvector('french', COALESCE(%s, '')), '%s')", source.column, source.weight))
+	}
+	return fmt.Sprintf("%s tsvector GENERATED ALWAYS AS (%s) STORED", SEARCH_VECTOR_COLUMN, strings.Join(parts, " || ")), nil
+}
+
+// addSearchVector adds the generated column to a table imported before it
+// existed. Postgres rewrites the whole table, which stays locked meanwhile.
+func addSearchVector(db *sql.DB, table string) error {
+	rows, err := db.Query(`SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1`, table)
+	if err != nil {
+		return fmt.Errorf("read columns of %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		columns = append(columns, name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if slices.Contains(columns, SEARCH_VECTOR_COLUMN) {
+		return nil
+	}
+	definition, err := searchVectorDefinition(table, columns)
+	if err != nil || definition == "" {
+		return err
+	}
+
+	start := time.Now()
+	fmt.Printf("Ajout de %s sur %s (reecriture de la table)...\n", SEARCH_VECTOR_COLUMN, table)
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, definition)); err != nil {
+		return fmt.Errorf("add %s to %s: %w", SEARCH_VECTOR_COLUMN, table, err)
+	}
+	fmt.Printf("%s ajoute sur %s en %.1fs\n", SEARCH_VECTOR_COLUMN, table, time.Since(start).Seconds())
+	return nil
+}
@@ -0,0 +1,225 @@
+package csv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Full imports load into <table>__staging and swap it with the live table in
+// one transaction; the replaced generation is kept as <table>__previous.
+const (
+	STAGING_SUFFIX  = "__staging"
+	PREVIOUS_SUFFIX = "__previous"
+	SWAP_SUFFIX     = "__swap"
+
+	// A staging table smaller than this fraction of the live table is
+	// considered a truncated import and is not swapped in.
+	MIN_SWAP_RATIO = 0.5
+)
+
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func tableExists(ctx context.Context, q rowQuerier, table string) (bool, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+	return exists, err
+}
+
+func ValidateStaging(db *sql.DB, table string, loaded int) error {
+	ctx := context.Background()
+	staging := table + STAGING_SUFFIX
+
+	var stagingCount int64
+	if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", staging)).Scan(&stagingCount); err != nil {
+		return fmt.Errorf("count %s: %w", staging, err)
+	}
+
+	if stagingCount == 0 {
+		return fmt.Errorf("%s is empty", staging)
+	}
+
+	if stagingCount != int64(loaded) {
+		return fmt.Errorf("%s has %d rows, expected %d", staging, stagingCount, loaded)
+	}
+
+	exists, err := tableExists(ctx, db, table)
+	if err != nil {
+		return fmt.Errorf("check %s: %w", table, err)
+	}
+	if !exists {
+		return nil
+	}
+
+	var liveCount int64
+	if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&liveCount); err != nil {
+		return fmt.Errorf("count %s: %w", table, err)
+	}
+
+	if float64(stagingCount) < float64(liveCount)*MIN_SWAP_RATIO {
+		return fmt.Errorf("%s has %d rows, less than %.0f%% of the %d live rows",
+			staging, stagingCount, MIN_SWAP_RATIO*100, liveCount)
+	}
+
+	fmt.Printf("Validation %s: %d lignes (live: %d)\n", table, stagingCount, liveCount)
+	return nil
+}
+
+func renameIndexes(ctx context.Context, tx *sql.Tx, table string, rename func(string) string) error {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = $1", table)
+	if err != nil {
+		return fmt.Errorf("list indexes of %s: %w", table, err)
+	}
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		newName := rename(name)
+		if newName == name {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER INDEX %s RENAME TO %s", name, newName)); err != nil {
+			return fmt.Errorf("rename index %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func renameTable(ctx context.Context, tx *sql.Tx, from, to string, rename func(string) string) error {
+	if err := renameIndexes(ctx, tx, from, rename); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s RENAME TO %s", from, to)); err != nil {
+		return fmt.Errorf("rename %s: %w", from, err)
+	}
+	return nil
+}
+
+func addSuffix(suffix string) func(string) string {
+	return func(name string) string { return name + suffix }
+}
+
+func replaceSuffix(old, suffix string) func(string) string {
+	return func(name string) string { return strings.TrimSuffix(name, old) + suffix }
+}
+
+// SwapTables promotes every <table>__staging to live and marks run as swapped
+// in a single transaction.
+func SwapTables(db *sql.DB, run *ImportRun, tables []string) error {
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin swap: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, table := range tables {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", table+PREVIOUS_SUFFIX)); err != nil {
+			return fmt.Errorf("drop previous %s: %w", table, err)
+		}
+
+		exists, err := tableExists(ctx, tx, table)
+		if err != nil {
+			return fmt.Errorf("check %s: %w", table, err)
+		}
+		if exists {
+			if err := renameTable(ctx, tx, table, table+PREVIOUS_SUFFIX, addSuffix(PREVIOUS_SUFFIX)); err != nil {
+				return err
+			}
+		}
+
+		if err := renameTable(ctx, tx, table+STAGING_SUFFIX, table, replaceSuffix(STAGING_SUFFIX, "")); err != nil {
+			return err
+		}
+	}
+
+	if err := run.markSwapped(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit swap: %w", err)
+	}
+
+	fmt.Printf("Bascule effectuee: %s (ancienne generation conservee en %s)\n",
+		strings.Join(tables, ", "), PREVIOUS_SUFFIX)
+	return nil
+}
+
+// RollbackTables exchanges live tables with their __previous generation.
+// Running it twice restores the state before the first rollback.
+func RollbackTables(db *sql.DB) error {
+	ctx := context.Background()
+
+	rows, err := db.QueryContext(ctx, `SELECT tablename FROM pg_tables
+		WHERE schemaname = current_schema() AND tablename LIKE '%\_\_previous' ORDER BY tablename`)
+	if err != nil {
+		return fmt.Errorf("list previous tables: %w", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		tables = append(tables, strings.TrimSuffix(name, PREVIOUS_SUFFIX))
+	}
+	_ = rows.Close()
+
+	if len(tables) == 0 {
+		return fmt.Errorf("no previous generation to roll back to")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin rollback: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, table := range tables {
+		exists, err := tableExists(ctx, tx, table)
+		if err != nil {
+			return fmt.Errorf("check %s: %w", table, err)
+		}
+
+		if exists {
+			if err := renameTable(ctx, tx, table, table+SWAP_SUFFIX, addSuffix(SWAP_SUFFIX)); err != nil {
+				return err
+			}
+		}
+
+		if err := renameTable(ctx, tx, table+PREVIOUS_SUFFIX, table, replaceSuffix(PREVIOUS_SUFFIX, "")); err != nil {
+			return err
+		}
+
+		if exists {
+			if err := renameTable(ctx, tx, table+SWAP_SUFFIX, table+PREVIOUS_SUFFIX, replaceSuffix(SWAP_SUFFIX, PREVIOUS_SUFFIX)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit rollback: %w", err)
+	}
+
+	fmt.Printf("Rollback effectue: %s\n", strings.Join(tables, ", "))
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/csv"
 	"fmt"
 	"path/filepath"
+	"sirene-importer/config"
 	"strings"
 	"time"
 )
@@ -41,10 +42,12 @@ func deriveTableName(zipName string) string {
 	name := strings.TrimSuffix(zipName, ".zip")
 	name = strings.TrimSuffix(name, "_utf8")
 
+	compact := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+
 	switch {
-	case strings.Contains(strings.ToLower(name), "unitelegale"):
+	case strings.Contains(compact, "unitelegale"):
 		return "unite_legale"
-	case strings.Contains(strings.ToLower(name), "etablissement"):
+	case strings.Contains(compact, "etablissement"):
 		return "etablissement"
 	default:
 		name = strings.ToLower(name)
@@ -54,7 +57,7 @@ func deriveTableName(zipName string) string {
 	}
 }
 
-func ProcessAllZIPs(db *sql.DB, zipDir string) error {
+func ProcessAllZIPs(db *sql.DB, zipDir string, resume bool) error {
 	zipFiles, err := getZIPFiles(zipDir)
 	if err != nil {
 		return fmt.Errorf("error scanning ZIP directory: %v", err)
@@ -64,37 +67,136 @@ func ProcessAllZIPs(db *sql.DB, zipDir string) error {
 		return fmt.Errorf("no ZIP files found in %s", zipDir)
 	}
 
+	run, err := StartImportRun(db, zipDir, resume)
+	if err != nil {
+		return err
+	}
+
+	if err := importZIPs(db, run, zipFiles); err != nil {
+		run.Finish(db, RUN_FAILED)
+		return err
+	}
+
+	run.Finish(db, RUN_COMPLETED)
+	return nil
+}
+
+func importZIPs(db *sql.DB, run *ImportRun, zipFiles []ZIPFile) error {
+	// The staging tables of a swapped run are live already and no step
+	// follows the swap, so there is nothing left to do.
+	if run.Swapped {
+		fmt.Printf("Import #%d deja bascule, rien a reprendre\n", run.ID)
+		return nil
+	}
+
 	fmt.Printf("Processing %d ZIP files\n", len(zipFiles))
 
 	totalStart := time.Now()
 
+	cfg := config.Load()
+	var tables []string
+	loaded := make(map[string]int)
+	rejected := make(map[string]string)
+	totalRejects := 0
+
 	for i, zf := range zipFiles {
+		checksum, err := fileChecksum(zf.Path)
+		if err != nil {
+			return fmt.Errorf("checksum %s: %v", zf.Name, err)
+		}
+
+		cp, err := run.checkpoint(db, zf.Name, zf.TableName, checksum, zipExtractDate(zf.Path))
+		if err != nil {
+			return err
+		}
+
+		tables = append(tables, zf.TableName)
+
+		if cp.status == FILE_LOADED {
+			loaded[zf.TableName] = int(cp.rows)
+			rejected[zf.TableName] = fmt.Sprintf("%d rejets (import precedent)", cp.rejects)
+			totalRejects += cp.rejects
+			fmt.Printf("\n[%d/%d] %s deja importe (%d lignes), ignore\n", i+1, len(zipFiles), zf.Name, cp.rows)
+			continue
+		}
+
 		fmt.Printf("\n[%d/%d] Processing %s...\n", i+1, len(zipFiles), zf.Name)
 
-		if err := ProcessZIPFile(db, zf.Path, zf.TableName); err != nil {
+		stagingTable := zf.TableName + STAGING_SUFFIX
+		rejects := NewRejectWriter(cfg.RejectsDir, zf.TableName)
+		totalLines, err := ProcessZIPFile(db, zf.Path, stagingTable, rejects, cp)
+		if closeErr := rejects.Close(); closeErr != nil {
+			fmt.Printf("Reject file error: %v\n", closeErr)
+		}
+		if err != nil {
 			return fmt.Errorf("error processing %s: %v", zf.Name, err)
 		}
 
-		fmt.Printf("[%d/%d] %s -> table '%s' completed\n", i+1, len(zipFiles), zf.Name, zf.TableName)
+		loaded[zf.TableName] = int(cp.rows) + totalLines
+		// cp.rejects holds the rejects of batches committed by an earlier
+		// attempt, which this run skipped.
+		fileRejects := cp.rejects + rejects.Total()
+		if err := cp.markLoaded(db, int64(loaded[zf.TableName]), fileRejects); err != nil {
+			return fmt.Errorf("checkpoint %s: %v", zf.Name, err)
+		}
+
+		rejected[zf.TableName] = rejects.Summary()
+		if cp.rejects > 0 {
+			rejected[zf.TableName] += fmt.Sprintf(" + %d (import precedent)", cp.rejects)
+		}
+		totalRejects += fileRejects
+		fmt.Printf("[%d/%d] %s -> table '%s' completed (%s)\n", i+1, len(zipFiles), zf.Name, stagingTable, rejects.Summary())
 	}
 
 	totalElapsed := time.Since(totalStart)
 	fmt.Printf("\nAll %d tables created in %.2f minutes\n", len(zipFiles), totalElapsed.Minutes())
 
+	fmt.Println("\nSUMMARY:")
+	for _, table := range tables {
+		fmt.Printf("   %s: %d lignes, %s\n", table, loaded[table], rejected[table])
+	}
+
+	if err := checkRejectThreshold(totalRejects, cfg.ImportMaxRejects); err != nil {
+		return fmt.Errorf("%v, live tables untouched", err)
+	}
+
 	fmt.Println("\nCr\u00e9ation des indexes...")
-	if err := CreateIndexes(db); err != nil {
-		fmt.Printf("Erreur indexes: %v\n", err)
+	if err := CreateStagingIndexes(db, tables, cfg.IndexWorkers); err != nil {
+		return fmt.Errorf("staging indexes failed, live tables untouched: %v", err)
+	}
+
+	for _, table := range tables {
+		if err := ValidateStaging(db, table, loaded[table]); err != nil {
+			return fmt.Errorf("validation failed, live tables untouched: %v", err)
+		}
+	}
+
+	return SwapTables(db, run, tables)
+}
+
+// zipExtractDate is the modification date of the CSV inside the archive,
+// which INSEE sets to the snapshot date of the stock file.
+func zipExtractDate(zipPath string) *time.Time {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil
 	}
+	defer func() { _ = r.Close() }()
 
+	for _, f := range r.File {
+		if strings.HasSuffix(strings.ToLower(f.Name), ".csv") && !f.Modified.IsZero() {
+			return &f.Modified
+		}
+	}
 	return nil
 }
 
-func ProcessZIPFile(db *sql.DB, zipPath, tableName string) error {
+func ProcessZIPFile(db *sql.DB, zipPath, tableName string, rejects *RejectWriter, cp *fileCheckpoint) (int, error) {
 	start := time.Now()
 
 	r, err := zip.OpenReader(zipPath)
 	if err != nil {
-		return fmt.Errorf("failed to open zip %s: %w", zipPath, err)
+		return 0, fmt.Errorf("failed to open zip %s: %w", zipPath, err)
 	}
 	defer func() { _ = r.Close() }()
 
@@ -107,7 +209,7 @@ func ProcessZIPFile(db *sql.DB, zipPath, tableName string) error {
 
 		rc, err := f.Open()
 		if err != nil {
-			return fmt.Errorf("failed to open CSV in zip: %w", err)
+			return 0, fmt.Errorf("failed to open CSV in zip: %w", err)
 		}
 		defer func() { _ = rc.Close() }()
 
@@ -117,28 +219,42 @@ func ProcessZIPFile(db *sql.DB, zipPath, tableName string) error {
 
 		headers, err := csvReader.Read()
 		if err != nil {
-			return fmt.Errorf("error reading headers: %w", err)
+			return 0, fmt.Errorf("error reading headers: %w", err)
 		}
 
 		fmt.Printf("CSV: %s (%d columns)\n", f.Name, len(headers))
 
-		cleanHeaders, columns := PrepareHeaders(headers)
+		schema, columns := PrepareHeaders(strings.TrimSuffix(tableName, STAGING_SUFFIX), headers)
+		definition, err := searchVectorDefinition(strings.TrimSuffix(tableName, STAGING_SUFFIX), schema.Headers)
+		if err != nil {
+			return 0, err
+		}
+		if definition != "" {
+			columns = append(columns, definition)
+		}
+
+		reuse, err := cp.canReuse(db, tableName)
+		if err != nil {
+			return 0, err
+		}
 
-		if err := setupTable(db, tableName, columns); err != nil {
-			return err
+		if reuse {
+			fmt.Printf("Reprise dans la table existante %s (%d lignes deja importees)\n", tableName, cp.rows)
+		} else if err := setupTable(db, tableName, columns); err != nil {
+			return 0, err
 		}
 
 		_ = rc.Close()
 
 		rc2, err := f.Open()
 		if err != nil {
-			return fmt.Errorf("failed to reopen CSV: %w", err)
+			return 0, fmt.Errorf("failed to reopen CSV: %w", err)
 		}
 		defer func() { _ = rc2.Close() }()
 
-		totalLines, err := ProcessPipelineFromReader(rc2, tableName, cleanHeaders)
+		totalLines, err := ProcessPipelineFromReader(rc2, tableName, schema, rejects, cp)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		elapsed := time.Since(start)
@@ -147,10 +263,10 @@ func ProcessZIPFile(db *sql.DB, zipPath, tableName string) error {
 		fmt.Printf("Done: %d lines in %.2f sec (%.0f lines/sec)\n",
 			totalLines, elapsed.Seconds(), linesPerSec)
 
-		return nil
+		return totalLines, nil
 	}
 
-	return fmt.Errorf("no CSV file found in %s", zipPath)
+	return 0, fmt.Errorf("no CSV file found in %s", zipPath)
 }
 
 func setupTable(db *sql.DB, tableName string, columns []string) error {
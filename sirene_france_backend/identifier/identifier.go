@@ -0,0 +1,117 @@
+// Package identifier validates and normalizes SIREN and SIRET numbers, which
+// carry a Luhn check digit.
+package identifier
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	SIREN_LENGTH = 9
+	SIRET_LENGTH = 14
+
+	// La Poste establishments outgrew the Luhn key: their SIRET is only
+	// required to have a digit sum divisible by 5. The headquarters
+	// (356 000 000 00048) predates the rule and keeps a Luhn key.
+	LA_POSTE_SIREN = "356000000"
+
+	KIND_SIREN = "siren"
+	KIND_SIRET = "siret"
+)
+
+// digits strips the separators users type (spaces, dots, dashes) from a
+// SIREN or SIRET.
+func digits(value string) string {
+	var sb strings.Builder
+	for _, r := range strings.TrimSpace(value) {
+		switch r {
+		case ' ', '.', '-', '\u00a0':
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func onlyDigits(number string) bool {
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return number != ""
+}
+
+func luhn(number string) bool {
+	sum := 0
+	for i := range number {
+		d := int(number[len(number)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+func digitSum(number string) int {
+	sum := 0
+	for i := range number {
+		sum += int(number[i] - '0')
+	}
+	return sum
+}
+
+// NormalizeSiren accepts a SIREN with or without separators
+// ("552 032 534") and returns its 9 digits.
+func NormalizeSiren(value string) (string, error) {
+	number := digits(value)
+	if len(number) != SIREN_LENGTH || !onlyDigits(number) {
+		return "", fmt.Errorf("invalid siren %q: expected 9 digits", value)
+	}
+	if !luhn(number) {
+		return "", fmt.Errorf("invalid siren %q: Luhn check digit does not match", value)
+	}
+	return number, nil
+}
+
+// NormalizeSiret accepts a SIRET with or without separators
+// ("443 061 841 00047") and returns its 14 digits.
+func NormalizeSiret(value string) (string, error) {
+	number := digits(value)
+	if len(number) != SIRET_LENGTH || !onlyDigits(number) {
+		return "", fmt.Errorf("invalid siret %q: expected 14 digits", value)
+	}
+
+	if number[:SIREN_LENGTH] == LA_POSTE_SIREN {
+		if digitSum(number)%5 != 0 && !luhn(number) {
+			return "", fmt.Errorf("invalid siret %q: La Poste digit sum is not a multiple of 5", value)
+		}
+		return number, nil
+	}
+
+	if !luhn(number[:SIREN_LENGTH]) {
+		return "", fmt.Errorf("invalid siret %q: Luhn check digit of its siren does not match", value)
+	}
+	if !luhn(number) {
+		return "", fmt.Errorf("invalid siret %q: Luhn check digit does not match", value)
+	}
+	return number, nil
+}
+
+// Normalize tells a SIREN from a SIRET by its digit count and normalizes it.
+func Normalize(value string) (kind, number string, err error) {
+	switch len(digits(value)) {
+	case SIREN_LENGTH:
+		number, err = NormalizeSiren(value)
+		return KIND_SIREN, number, err
+	case SIRET_LENGTH:
+		number, err = NormalizeSiret(value)
+		return KIND_SIRET, number, err
+	}
+	return "", "", fmt.Errorf("invalid identifier %q: expected a 9-digit siren or a 14-digit siret", value)
+}
@@ -0,0 +1,71 @@
+package identifier
+
+import "testing"
+
+func TestLuhn(t *testing.T) {
+	tests := []struct {
+		number string
+		valid  bool
+	}{
+		{"552032534", true},
+		{"443061841", true},
+		{"44306184100047", true},
+		{"54205118000066", true},
+		{"552032535", false},
+		{"44306184100048", false},
+		{"44306184100074", false},
+	}
+
+	for _, tt := range tests {
+		if got := luhn(tt.number); got != tt.valid {
+			t.Errorf("luhn(%q) = %v, want %v", tt.number, got, tt.valid)
+		}
+	}
+}
+
+func TestNormalizeSiren(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		valid bool
+	}{
+		{"552032534", "552032534", true},
+		{"552 032 534", "552032534", true},
+		{"443.061.841", "443061841", true},
+		{"552032535", "", false},
+		{"55203253", "", false},
+		{"55203253A", "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeSiren(tt.value)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("NormalizeSiren(%q) = %q, %v, want %q, valid %v", tt.value, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestNormalizeSiret(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		valid bool
+	}{
+		{"44306184100047", "44306184100047", true},
+		{"443 061 841 00047", "44306184100047", true},
+		{"542-051-180-00066", "54205118000066", true},
+		{"35600000000048", "35600000000048", true},
+		{"35600000049837", "35600000049837", true},
+		{"44306184100048", "", false},
+		{"55203253400013", "", false},
+		{"35600000049838", "", false},
+		{"4430618410004", "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeSiret(tt.value)
+		if (err == nil) != tt.valid || got != tt.want {
+			t.Errorf("NormalizeSiret(%q) = %q, %v, want %q, valid %v", tt.value, got, err, tt.want, tt.valid)
+		}
+	}
+}
@@ -5,6 +5,7 @@ export interface Meta {
   offset?: number;
   page?: number;
   pages?: number;
+  next_cursor?: string;
   duration_ms?: number;
 }
 
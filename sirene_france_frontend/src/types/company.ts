@@ -21,6 +21,8 @@ export interface CompanyResult {
   website?: string;
   unite_legale?: Record<string, unknown>;
   etablissements?: Record<string, unknown>[];
+  restricted: boolean;
+  restricted_fields?: string[];
 }
 
 export interface CompanySearchCriteria {